@@ -8,7 +8,11 @@ import (
 	"log"
 	"micro-golang/internal/auth"
 	"micro-golang/internal/config"
+	"micro-golang/internal/mail"
 	"micro-golang/internal/middlewares"
+	"micro-golang/internal/models"
+	"micro-golang/internal/rbac"
+	"micro-golang/internal/user"
 	"time"
 )
 
@@ -22,21 +26,25 @@ import (
  * @Version:  1.0
  */
 
-var jwtKey = []byte("A9v$34Fjfl1.pMv@z6k1!qwe93Km!")
-
 func main() {
 
 	// 新增log 完整資訊
 	initializeLogger()
 	// DB初始化
 	config.ConnectDB()
+	// 資料表結構更新
+	if err := models.AutoMigrate(config.DB); err != nil {
+		log.Fatalf("❌ 資料表更新失敗：%v", err)
+	}
 	// Redis 初始化
 	config.InitRedis()
+	// JWT 簽章金鑰初始化（含定期輪替）
+	config.InitJWTSigner()
 
 	// 驗證器設定
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
-		// 註冊密碼驗證器
-		_ = v.RegisterValidation("pwd_validation", middlewares.UserPwd)
+		// 註冊密碼規則驗證器（規則可由環境變數調整）
+		middlewares.RegisterPasswordValidations(v)
 		// 註冊使用者名稱驗證器
 		_ = v.RegisterValidation("username_validation", middlewares.UserName)
 	}
@@ -48,15 +56,81 @@ func main() {
 	// 跨域設定
 	setupCorsMiddleware(r)
 
+	mailer := mail.NewFromEnv()
+	ah := auth.NewHandler(auth.NewService(mailer, auth.IdentityProvidersFromEnv()), user.NewService(config.DB, config.RDB, mailer))
+
+	// 公開驗章用的公鑰
+	r.GET("/.well-known/jwks.json", ah.JWKS)
+	// OpenID Connect
+	r.GET("/.well-known/openid-configuration", ah.OpenIDConfiguration)
+	r.GET("/userinfo", middlewares.JWTAuth(), ah.UserInfo)
+
 	authGroup := r.Group("/auth")
-	ah := auth.NewHandler(auth.Service{})
 	authGroup.POST("/login", ah.Login)
 	authGroup.POST("/register", ah.Register)
-	authGroup.POST("/refresh", ah.RefreshToken)
+	// Email 驗證
+	authGroup.GET("/verify", ah.VerifyEmail)
+	authGroup.POST("/verify/resend", ah.ResendVerification)
+	// 變更 Email 的確認連結（寄到新 Email）
+	authGroup.GET("/email-change/confirm", ah.ConfirmEmailChange)
+	// Cookie 登入模式從 cookie 讀取 refresh token，需通過 CSRF 檢查
+	authGroup.POST("/refresh", middlewares.CSRFProtect(), ah.RefreshToken)
 	authGroup.GET("/ping", func(c *gin.Context) {
 		c.JSON(200, gin.H{"message": "測試是否自動部署"})
 	})
-	authGroup.POST("/logout", ah.LogoutHandler)
+	authGroup.POST("/logout", middlewares.CSRFProtect(), ah.LogoutHandler)
+	// Token 查詢 / 撤銷（RFC 7662 / RFC 7009），需以 OAuth client 驗證
+	authGroup.POST("/introspect", ah.Introspect)
+	authGroup.POST("/revoke", ah.Revoke)
+	// 免密碼登入連結
+	authGroup.POST("/magic-link", ah.RequestMagicLink)
+	authGroup.GET("/magic-link/consume", ah.ConsumeMagicLink)
+	// 外部身分提供者登入（Google、GitLab、Keycloak 等 OIDC）
+	authGroup.GET("/external", ah.ListIdentityProviders)
+	authGroup.GET("/external/:provider/login", ah.ExternalLogin)
+	authGroup.GET("/external/:provider/callback", ah.ExternalCallback)
+	// 忘記密碼 / 重設密碼
+	authGroup.POST("/password/forgot", ah.ForgotPassword)
+	authGroup.POST("/password/reset", ah.ResetPassword)
+
+	// 兩步驟驗證：登入第二步不需 access token，設定 / 確認 / 停用需登入
+	// 尚未設定 TOTP 的管理者只拿得到限定設定用的 token，只有這組路由接受
+	authGroup.POST("/mfa/verify", ah.VerifyMFA)
+	mfaGroup := authGroup.Group("/mfa/totp", middlewares.MFASetupAuth(), middlewares.DenyAPIKey(), middlewares.DenyImpersonation())
+	mfaGroup.POST("/setup", ah.SetupTOTP)
+	mfaGroup.POST("/confirm", ah.ConfirmTOTP)
+	mfaGroup.POST("/disable", ah.DisableTOTP)
+
+	// 登入裝置管理（需登入）
+	sessionGroup := authGroup.Group("/sessions", middlewares.JWTAuth(), middlewares.DenyAPIKey(), middlewares.DenyImpersonation())
+	sessionGroup.GET("", ah.ListSessions)
+	sessionGroup.DELETE("", ah.RevokeAllSessions)
+	sessionGroup.DELETE("/:id", ah.RevokeSession)
+
+	// 個人 API 金鑰管理（需以帳號登入）
+	apiKeyGroup := authGroup.Group("/api-keys", middlewares.JWTAuth(), middlewares.DenyAPIKey(), middlewares.DenyImpersonation())
+	apiKeyGroup.POST("", ah.CreateAPIKey)
+	apiKeyGroup.GET("", ah.ListAPIKeys)
+	apiKeyGroup.DELETE("/:id", ah.RevokeAPIKey)
+
+	// OAuth 2.1 授權伺服器
+	oauthGroup := r.Group("/oauth")
+	oauthGroup.GET("/authorize", ah.Authorize)
+	oauthGroup.POST("/authorize", ah.AuthorizeSubmit)
+	oauthGroup.POST("/token", ah.Token)
+
+	adminGroup := r.Group("/admin", middlewares.JWTAuth())
+	// 管理者解除登入鎖定
+	adminGroup.POST("/lockouts/unlock", middlewares.RequirePermission(rbac.PermUserManage), ah.UnlockLogin)
+	// 管理者代理登入（需以帳號登入，不可在代理中再次代理）
+	adminGroup.POST("/impersonate/:userId", middlewares.DenyAPIKey(), middlewares.DenyImpersonation(),
+		middlewares.RequirePermission(rbac.PermUserImpersonate), ah.Impersonate)
+	// OAuth client 註冊（需以帳號登入）
+	clientGroup := adminGroup.Group("/oauth/clients", middlewares.DenyAPIKey(), middlewares.RequirePermission(rbac.PermOAuthClientManage))
+	clientGroup.POST("", ah.CreateOAuthClient)
+	clientGroup.GET("", ah.ListOAuthClients)
+	clientGroup.DELETE("/:clientId", ah.DeleteOAuthClient)
+
 	err := r.Run(":7001")
 	if err != nil {
 		return
@@ -72,7 +146,7 @@ func setupCorsMiddleware(r *gin.Engine) {
 			"https://taguo1109.github.io", // GitHub Pages 正式站
 		},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-CSRF-Token", "X-Auth-Mode"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true, // 如果你有用 cookie/token
 		MaxAge:           12 * time.Hour,
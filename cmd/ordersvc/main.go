@@ -6,6 +6,7 @@ import (
 	"micro-golang/internal/config"
 	"micro-golang/internal/middlewares"
 	"micro-golang/internal/order"
+	"micro-golang/internal/rbac"
 	"os"
 )
 
@@ -28,6 +29,8 @@ func main() {
 	config.ConnectDB()
 	// Redis 初始化
 	config.InitRedis()
+	// JWT 驗章（透過 authsvc 的 JWKS）
+	config.InitJWTVerifier()
 
 	port := os.Getenv("ORDER_PORT")
 	if port == "" {
@@ -43,8 +46,9 @@ func main() {
 	r := gin.Default()
 	r.Use(middlewares.JWTAuth())
 	oh := order.NewHandler(userSvcURL)
-	r.GET("/orders/:id", oh.GetOrder)
-	r.GET("/orders/email/:id", oh.GetOrderWithEmail)
+	og := r.Group("/orders", middlewares.RequirePermission(rbac.PermOrderRead))
+	og.GET("/:id", oh.GetOrder)
+	og.GET("/email/:id", oh.GetOrderWithEmail)
 
 	log.Printf("Order services running on :%s\n", port)
 	log.Fatal(r.Run(":" + port))
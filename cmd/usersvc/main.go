@@ -1,11 +1,16 @@
 package main
 
 import (
+	"context"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
 	"log"
 	"micro-golang/internal/config"
+	"micro-golang/internal/mail"
 	"micro-golang/internal/middlewares"
+	"micro-golang/internal/rbac"
 	"micro-golang/internal/user"
 	"net/http"
 	"os"
@@ -30,6 +35,16 @@ func main() {
 	config.ConnectDB()
 	// Redis 初始化
 	config.InitRedis()
+	// JWT 驗章（透過 authsvc 的 JWKS）
+	config.InitJWTVerifier()
+
+	// 驗證器設定
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		// 註冊密碼規則驗證器（規則可由環境變數調整）
+		middlewares.RegisterPasswordValidations(v)
+		// 註冊使用者名稱驗證器
+		_ = v.RegisterValidation("username_validation", middlewares.UserName)
+	}
 
 	port := os.Getenv("USER_PORT")
 	if port == "" {
@@ -52,18 +67,42 @@ func main() {
 	redisClientInstance := config.RDB // 你的 *redis.Client 實例
 	// 2. 創建 user.Service 的實例
 	// NewService 是在你的 user 套件中定義的
-	userServiceInstance := user.NewService(dbInstance, redisClientInstance)
+	userServiceInstance := user.NewService(dbInstance, redisClientInstance, mail.NewFromEnv())
 	// 3. 創建 user.Handler 的實例，並傳入 userServiceInstance
 	// NewHandler 也是在你的 user 套件中定義的
 	uh := user.NewHandler(userServiceInstance)
+	// 背景排程：匿名化超過寬限期的已刪除帳號
+	userServiceInstance.StartPurge(context.Background())
 
 	ur := r.Group("/users")
-	ur.GET("/:id", uh.GetUser)
-	ur.GET("/email/:id", uh.GetUserEmail)
+	// 依 ID 查詢使用者：一般使用者只能查自己，需 user:read:any 才能查其他人
+	ur.GET("/:id", middlewares.RequirePermission(rbac.PermUserRead), middlewares.RequireSelfOrPermission("id", rbac.PermUserReadAny), uh.GetUser)
+	ur.GET("/email/:id", middlewares.RequirePermission(rbac.PermUserRead), middlewares.RequireSelfOrPermission("id", rbac.PermUserReadAny), uh.GetUserEmail)
 	// 獲取個人資料
-	ur.GET("/profile", uh.GetProfile)
-	// 更新個人資料
-	ur.PUT("/profile", uh.UpdateProfile)
+	ur.GET("/profile", middlewares.RequirePermission(rbac.PermProfileRead), uh.GetProfile)
+	// 更新個人資料（可變更 Email，API 金鑰、代理登入不可使用）
+	ur.PUT("/profile", middlewares.DenyAPIKey(), middlewares.DenyImpersonation(), middlewares.RequirePermission(rbac.PermProfileWrite), uh.UpdateProfile)
+	// 變更密碼（API 金鑰、代理登入不可使用）
+	ur.PUT("/password", middlewares.DenyAPIKey(), middlewares.DenyImpersonation(), middlewares.RequirePermission(rbac.PermProfileWrite), uh.ChangePassword)
+	// 刪除自己的帳號（API 金鑰、代理登入不可使用）
+	ur.DELETE("/me", middlewares.DenyAPIKey(), middlewares.DenyImpersonation(), middlewares.RequirePermission(rbac.PermProfileWrite), uh.DeleteAccount)
+	// 個資匯出 / 刪除（GDPR），在背景執行，以工作 ID 查詢進度
+	me := ur.Group("/me", middlewares.DenyAPIKey(), middlewares.DenyImpersonation())
+	me.POST("/export", middlewares.RequirePermission(rbac.PermProfileRead), uh.ExportData)
+	me.POST("/erase", middlewares.RequirePermission(rbac.PermProfileWrite), uh.EraseData)
+	me.GET("/data-requests/:id", middlewares.RequirePermission(rbac.PermProfileRead), uh.GetDataRequest)
+	me.GET("/data-requests/:id/download", middlewares.RequirePermission(rbac.PermProfileRead), uh.DownloadDataExport)
+
+	// 管理者功能：瀏覽使用者、建立帳號、調整角色、停用 / 啟用 / 復原帳號、重設兩步驟驗證
+	ag := r.Group("/admin", middlewares.RequirePermission(rbac.PermUserManage))
+	ag.GET("/users", uh.ListUsers)
+	ag.POST("/users", uh.CreateUser)
+	ag.PUT("/users/:id/role", uh.ChangeUserRole)
+	ag.POST("/users/:id/deactivate", uh.DeactivateUser)
+	ag.POST("/users/:id/reactivate", uh.ReactivateUser)
+	ag.POST("/users/:id/restore", uh.RestoreUser)
+	ag.POST("/users/:id/mfa/reset", uh.ResetUserMFA)
+	ag.GET("/data-requests/:id", uh.AdminGetDataRequest)
 
 	ur.GET("/api/v1/users/:id", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
@@ -85,7 +124,7 @@ func setupCorsMiddleware(r *gin.Engine) {
 			"https://taguo1109.github.io", // GitHub Pages 正式站
 		},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-CSRF-Token", "X-Auth-Mode"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true, // 如果你有用 cookie/token
 		MaxAge:           12 * time.Hour,
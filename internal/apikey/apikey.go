@@ -0,0 +1,242 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"log"
+	"micro-golang/internal/config"
+	"micro-golang/internal/dto"
+	"micro-golang/internal/models"
+	"micro-golang/internal/rbac"
+	"strings"
+	"time"
+)
+
+/**
+ * @File: apikey.go
+ * @Description:
+ *
+ * 個人 API 金鑰：建立 / 列出 / 撤銷，以及各服務驗證金鑰用的 Authenticate
+ * 金鑰格式為 mgk_<prefix>_<secret>，資料庫只保存 SHA-256 雜湊值與公開的 prefix。
+ * 驗證結果會在 Redis 快取 cacheTTL，撤銷時一併清除快取。
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/19 下午6:10
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+const (
+	keyPrefix      = "mgk_"
+	cachePrefix    = "apikey:" // apikey:<key hash> → Principal JSON
+	cacheTTL       = time.Minute
+	maxKeysPerUser = 20
+)
+
+var (
+	ErrInvalidKey    = errors.New("invalid api key")
+	ErrInvalidScope  = errors.New("scope not allowed for this user")
+	ErrInvalidExpiry = errors.New("expiry must be in the future")
+	ErrTooManyKeys   = errors.New("too many api keys")
+	ErrNotFound      = errors.New("api key not found")
+)
+
+// Principal 以 API 金鑰驗證通過的身分
+type Principal struct {
+	KeyID  uint              `json:"key_id"`
+	UserID uint              `json:"user_id"`
+	Email  string            `json:"email"`
+	Role   string            `json:"role"`
+	Scopes []rbac.Permission `json:"scopes"`
+}
+
+// Create 為使用者建立 API 金鑰，scopes 不可超出使用者角色本身的權限
+// 回傳的 Key 為完整金鑰，之後無法再取得
+func Create(ctx context.Context, user models.User, req dto.APIKeyCreateDTO) (*dto.APIKeyCreatedDTO, error) {
+	for _, s := range req.Scopes {
+		if !rbac.HasPermission(user.Role, rbac.Permission(s)) {
+			return nil, ErrInvalidScope
+		}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, ErrInvalidExpiry
+	}
+
+	var count int64
+	err := config.DB.WithContext(ctx).Model(&models.APIKey{}).
+		Where("user_id = ? AND revoked_at IS NULL", user.ID).Count(&count).Error
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxKeysPerUser {
+		return nil, ErrTooManyKeys
+	}
+
+	prefix, key := newKey()
+	row := models.APIKey{
+		UserID:    user.ID,
+		Name:      req.Name,
+		Prefix:    prefix,
+		KeyHash:   hashKey(key),
+		Scopes:    strings.Join(req.Scopes, ","),
+		ExpiresAt: req.ExpiresAt,
+	}
+	if err := config.DB.WithContext(ctx).Create(&row).Error; err != nil {
+		return nil, err
+	}
+	return &dto.APIKeyCreatedDTO{APIKeyDTO: toDTO(row), Key: key}, nil
+}
+
+// List 列出使用者尚未撤銷的金鑰
+func List(ctx context.Context, userID uint) ([]dto.APIKeyDTO, error) {
+	var rows []models.APIKey
+	err := config.DB.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("created_at DESC").Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	result := make([]dto.APIKeyDTO, 0, len(rows))
+	for _, r := range rows {
+		result = append(result, toDTO(r))
+	}
+	return result, nil
+}
+
+// Revoke 撤銷使用者的一把金鑰
+func Revoke(ctx context.Context, userID uint, id uint) error {
+	var row models.APIKey
+	err := config.DB.WithContext(ctx).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).First(&row).Error
+	if err != nil {
+		return ErrNotFound
+	}
+	if err := config.DB.WithContext(ctx).Model(&row).Update("revoked_at", time.Now()).Error; err != nil {
+		return err
+	}
+	config.RDB.Del(ctx, cachePrefix+row.KeyHash)
+	return nil
+}
+
+// EvictUser 清除使用者所有金鑰的驗證快取（帳號停用時立即生效，金鑰本身保留）
+func EvictUser(ctx context.Context, userID uint) error {
+	var hashes []string
+	if err := config.DB.WithContext(ctx).Model(&models.APIKey{}).
+		Where("user_id = ?", userID).Pluck("key_hash", &hashes).Error; err != nil {
+		return err
+	}
+	if len(hashes) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(hashes))
+	for _, h := range hashes {
+		keys = append(keys, cachePrefix+h)
+	}
+	return config.RDB.Del(ctx, keys...).Err()
+}
+
+// RevokeAll 撤銷使用者所有金鑰（刪除帳號時使用）
+func RevokeAll(ctx context.Context, userID uint) error {
+	if err := config.DB.WithContext(ctx).Model(&models.APIKey{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).Update("revoked_at", time.Now()).Error; err != nil {
+		return err
+	}
+	return EvictUser(ctx, userID)
+}
+
+// Authenticate 驗證金鑰並取得對應的使用者身分
+// 使用者的角色以驗證當下為準，角色被降級後超出權限的 scope 也會一併失效（由 RequirePermission 檢查）
+func Authenticate(ctx context.Context, key string) (*Principal, error) {
+	if !strings.HasPrefix(key, keyPrefix) {
+		return nil, ErrInvalidKey
+	}
+	hash := hashKey(key)
+
+	if cached, err := config.RDB.Get(ctx, cachePrefix+hash).Bytes(); err == nil {
+		var p Principal
+		if json.Unmarshal(cached, &p) == nil {
+			return &p, nil
+		}
+	} else if !errors.Is(err, redis.Nil) {
+		log.Printf("⚠️ 讀取 API 金鑰快取失敗：%v", err)
+	}
+
+	var row models.APIKey
+	if err := config.DB.WithContext(ctx).Where("key_hash = ?", hash).First(&row).Error; err != nil {
+		return nil, ErrInvalidKey
+	}
+	if row.RevokedAt != nil || (row.ExpiresAt != nil && row.ExpiresAt.Before(time.Now())) {
+		return nil, ErrInvalidKey
+	}
+	var user models.User
+	if err := config.DB.WithContext(ctx).First(&user, row.UserID).Error; err != nil || !user.IsActive {
+		return nil, ErrInvalidKey
+	}
+
+	p := &Principal{
+		KeyID:  row.ID,
+		UserID: user.ID,
+		Email:  user.Email,
+		Role:   user.Role,
+		Scopes: parseScopes(row.Scopes),
+	}
+
+	// 快取期間不更新 last_used_at，等同每分鐘最多寫一次資料庫
+	config.DB.WithContext(ctx).Model(&row).UpdateColumn("last_used_at", time.Now())
+	ttl := cacheTTL
+	if row.ExpiresAt != nil && time.Until(*row.ExpiresAt) < ttl {
+		ttl = time.Until(*row.ExpiresAt)
+	}
+	if b, err := json.Marshal(p); err == nil {
+		config.RDB.Set(ctx, cachePrefix+hash, b, ttl)
+	}
+	return p, nil
+}
+
+// newKey 產生金鑰，回傳公開 prefix 與完整金鑰
+func newKey() (string, string) {
+	p := make([]byte, 4)
+	s := make([]byte, 32)
+	_, _ = rand.Read(p)
+	_, _ = rand.Read(s)
+	prefix := hex.EncodeToString(p)
+	return prefix, keyPrefix + prefix + "_" + base64.RawURLEncoding.EncodeToString(s)
+}
+
+// hashKey 金鑰本身為高熵亂數，直接使用 SHA-256 即可
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func parseScopes(s string) []rbac.Permission {
+	var scopes []rbac.Permission
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			scopes = append(scopes, rbac.Permission(v))
+		}
+	}
+	return scopes
+}
+
+func toDTO(r models.APIKey) dto.APIKeyDTO {
+	scopes := make([]string, 0)
+	for _, s := range parseScopes(r.Scopes) {
+		scopes = append(scopes, string(s))
+	}
+	return dto.APIKeyDTO{
+		ID:         r.ID,
+		Name:       r.Name,
+		Prefix:     keyPrefix + r.Prefix,
+		Scopes:     scopes,
+		ExpiresAt:  r.ExpiresAt,
+		LastUsedAt: r.LastUsedAt,
+		CreatedAt:  r.CreatedAt,
+	}
+}
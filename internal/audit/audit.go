@@ -0,0 +1,117 @@
+package audit
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"micro-golang/internal/models"
+	"micro-golang/internal/utils"
+	"strconv"
+	"strings"
+)
+
+/**
+ * @File: audit.go
+ * @Description:
+ *
+ * 稽核紀錄寫入，可傳入交易中的 *gorm.DB，讓業務資料與稽核紀錄一起 commit
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/19 下午3:20
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// 稽核動作名稱
+const (
+	ActionUserCreate        = "user.create"
+	ActionRoleChange        = "user.role_change"
+	ActionUserDeactivate    = "user.deactivate"
+	ActionUserReactivate    = "user.reactivate"
+	ActionUserDelete        = "user.delete"
+	ActionUserRestore       = "user.restore"
+	ActionUserAnonymize     = "user.anonymize"
+	ActionEmailChange       = "user.email_change"
+	ActionMFADisable        = "user.mfa_disable"
+	ActionMFAReset          = "user.mfa_reset"
+	ActionLoginUnlock       = "auth.login_unlock"
+	ActionOAuthClientCreate = "oauth_client.create"
+	ActionOAuthClientDelete = "oauth_client.delete"
+	ActionIdentityLink      = "auth.identity_link"
+	ActionImpersonate       = "auth.impersonate"
+	ActionImpersonatedCall  = "auth.impersonated_request"
+)
+
+// Actor 執行操作的人
+type Actor struct {
+	ID   uint
+	Role string
+	IP   string
+}
+
+// ActorFromContext 從 JWTAuth 放入的 context 取出目前操作者
+// 代理登入（impersonation）時記錄的是實際操作的管理者，而不是被代理的使用者
+func ActorFromContext(c *gin.Context) Actor {
+	if id, ok := utils.GetActorID(c); ok {
+		return Actor{
+			ID:   id,
+			Role: c.GetString("actorRole"),
+			IP:   c.ClientIP(),
+		}
+	}
+	id, _ := utils.GetUserID(c)
+	return Actor{
+		ID:   id,
+		Role: c.GetString("role"),
+		IP:   c.ClientIP(),
+	}
+}
+
+// Entry 一筆稽核紀錄
+type Entry struct {
+	Actor      Actor
+	Action     string
+	TargetType string
+	TargetID   string
+	Detail     interface{} // 會轉成 JSON 存放
+}
+
+// Record 寫入稽核紀錄
+func Record(db *gorm.DB, e Entry) error {
+	detail := ""
+	if e.Detail != nil {
+		b, err := json.Marshal(e.Detail)
+		if err != nil {
+			return err
+		}
+		detail = string(b)
+	}
+	return db.Create(&models.AuditLog{
+		ActorID:    e.Actor.ID,
+		ActorRole:  e.Actor.Role,
+		Action:     e.Action,
+		TargetType: e.TargetType,
+		TargetID:   e.TargetID,
+		Detail:     detail,
+		IP:         e.Actor.IP,
+	}).Error
+}
+
+// ScrubSubject 清除稽核紀錄中與使用者相關的個資（刪除個資時使用）
+// 紀錄本身與 actor_id / target_id 保留，只清除使用者操作時的 IP、Email 變更紀錄，以及內容含有其 Email 的 detail
+func ScrubSubject(db *gorm.DB, userID uint, email string) error {
+	if err := db.Model(&models.AuditLog{}).Where("actor_id = ?", userID).Update("ip", "").Error; err != nil {
+		return err
+	}
+	// Email 變更紀錄含有過去使用過的 Email，不一定是目前這個
+	if err := db.Model(&models.AuditLog{}).
+		Where("action = ? AND target_type = ? AND target_id = ?", ActionEmailChange, "user", strconv.FormatUint(uint64(userID), 10)).
+		Update("detail", "").Error; err != nil {
+		return err
+	}
+	if email == "" {
+		return nil
+	}
+	pattern := "%" + strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(email) + "%"
+	return db.Model(&models.AuditLog{}).Where("detail LIKE ?", pattern).Update("detail", "").Error
+}
@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"micro-golang/internal/apikey"
+	"micro-golang/internal/config"
+	"micro-golang/internal/dto"
+	"micro-golang/internal/models"
+	"micro-golang/internal/utils"
+	"strconv"
+)
+
+/**
+ * @File: apikey_handler.go
+ * @Description:
+ *
+ * 個人 API 金鑰管理（/auth/api-keys），需以帳號登入，不可用 API 金鑰呼叫
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/19 下午6:40
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// CreateAPIKey 建立 API 金鑰，完整金鑰只會在這次回應中出現
+func (h *Handler) CreateAPIKey(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		utils.ReturnError(c, utils.CodeUnauthorized, nil, "Token 內容無效")
+		return
+	}
+
+	var req dto.APIKeyCreateDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		var ve validator.ValidationErrors
+		if errors.As(err, &ve) {
+			utils.ReturnError(c, utils.CodeParamInvalid, utils.ExtractFieldErrorMessages(req, ve), "欄位驗證失敗")
+			return
+		}
+		utils.ReturnError(c, utils.CodeParamInvalid, err.Error())
+		return
+	}
+
+	// 以資料庫的角色為準，避免 token 內的角色已過時
+	var user models.User
+	if err := config.DB.WithContext(c).First(&user, userID).Error; err != nil {
+		utils.ReturnError(c, utils.CodeUnauthorized, nil, "找不到使用者")
+		return
+	}
+
+	created, err := apikey.Create(c, user, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, apikey.ErrInvalidScope):
+			utils.ReturnError(c, utils.CodeForbidden, nil, "scopes 不可超出目前角色擁有的權限")
+		case errors.Is(err, apikey.ErrInvalidExpiry):
+			utils.ReturnError(c, utils.CodeParamInvalid, nil, "到期時間必須晚於現在")
+		case errors.Is(err, apikey.ErrTooManyKeys):
+			utils.ReturnError(c, utils.CodeBadRequest, nil, "API 金鑰數量已達上限，請先撤銷不用的金鑰")
+		default:
+			utils.ReturnError(c, utils.CodeServerError, nil, "建立 API 金鑰失敗")
+		}
+		return
+	}
+	utils.ReturnSuccess(c, created, "請妥善保存 API 金鑰，之後將無法再次查看")
+}
+
+// ListAPIKeys 列出目前使用者的 API 金鑰
+func (h *Handler) ListAPIKeys(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		utils.ReturnError(c, utils.CodeUnauthorized, nil, "Token 內容無效")
+		return
+	}
+
+	keys, err := apikey.List(c, userID)
+	if err != nil {
+		utils.ReturnError(c, utils.CodeServerError, nil, "無法取得 API 金鑰")
+		return
+	}
+	utils.ReturnSuccess(c, keys)
+}
+
+// RevokeAPIKey 撤銷指定的 API 金鑰
+func (h *Handler) RevokeAPIKey(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		utils.ReturnError(c, utils.CodeUnauthorized, nil, "Token 內容無效")
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ReturnError(c, utils.CodeParamInvalid, nil, "金鑰 ID 格式錯誤")
+		return
+	}
+
+	if err := apikey.Revoke(c, userID, uint(id)); err != nil {
+		if errors.Is(err, apikey.ErrNotFound) {
+			utils.ReturnError(c, utils.CodeNotFound, nil, "找不到此 API 金鑰")
+			return
+		}
+		utils.ReturnError(c, utils.CodeServerError, nil, "撤銷 API 金鑰失敗")
+		return
+	}
+	utils.ReturnSuccess(c, nil, "API key revoked")
+}
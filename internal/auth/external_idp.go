@@ -0,0 +1,284 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"log"
+	"micro-golang/internal/config"
+	"micro-golang/internal/utils"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+/**
+ * @File: external_idp.go
+ * @Description:
+ *
+ * 外部 OIDC 身分提供者（Google、GitLab、Keycloak 等）的客戶端
+ * 只需設定 issuer，端點與公鑰由 <issuer>/.well-known/openid-configuration 取得；
+ * 一律使用 authorization_code + PKCE，並以 IdP 的 JWKS 驗證 ID token 的簽章、iss、aud、exp 與 nonce。
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/21 上午10:20
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// idpJWKSTTL IdP 公鑰快取時間，遇到未知 kid 時 JWKSClient 會自行重新抓取
+const idpJWKSTTL = time.Hour
+
+var defaultIdPScopes = []string{"openid", "email", "profile"}
+
+// IdentityProviderConfig 外部身分提供者設定
+type IdentityProviderConfig struct {
+	Name         string // 路由使用的名稱，例如 google → /auth/external/google/login
+	DisplayName  string // 登入按鈕顯示的名稱
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	RedirectURL  string // 需與在 IdP 註冊的 redirect URI 完全相同
+}
+
+// IdentityProvider 外部身分提供者，第一次使用時才抓取 discovery 文件
+type IdentityProvider struct {
+	cfg        IdentityProviderConfig
+	httpClient *http.Client
+
+	mu       sync.Mutex
+	metadata *providerMetadata
+	jwks     *utils.JWKSClient
+}
+
+// providerMetadata discovery 文件中需要的欄位
+type providerMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// externalClaims ID token 中用來建立 / 連結本地帳號的資料
+type externalClaims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// NewIdentityProvider 建立外部身分提供者，未指定的 scope / redirect URL 使用預設值
+func NewIdentityProvider(cfg IdentityProviderConfig) *IdentityProvider {
+	cfg.Issuer = strings.TrimRight(cfg.Issuer, "/")
+	if cfg.DisplayName == "" {
+		cfg.DisplayName = cfg.Name
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = defaultIdPScopes
+	}
+	if cfg.RedirectURL == "" {
+		cfg.RedirectURL = issuerURL() + "/auth/external/" + cfg.Name + "/callback"
+	}
+	return &IdentityProvider{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// IdentityProvidersFromEnv 依環境變數建立外部身分提供者
+//
+// EXTERNAL_IDPS                 啟用的 IdP 名稱，以逗號分隔（例如 google,gitlab）
+// IDP_<NAME>_ISSUER             issuer 網址
+// IDP_<NAME>_CLIENT_ID、IDP_<NAME>_CLIENT_SECRET  在 IdP 註冊的 client
+// IDP_<NAME>_SCOPES             以空白分隔，預設 openid email profile
+// IDP_<NAME>_DISPLAY_NAME       顯示名稱，預設為 IdP 名稱
+// IDP_<NAME>_REDIRECT_URL       預設 <AUTH_BASE_URL>/auth/external/<name>/callback
+func IdentityProvidersFromEnv() []*IdentityProvider {
+	var providers []*IdentityProvider
+	for _, name := range strings.Split(config.GetEnv("EXTERNAL_IDPS", ""), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		prefix := "IDP_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		cfg := IdentityProviderConfig{
+			Name:         name,
+			DisplayName:  config.GetEnv(prefix+"DISPLAY_NAME", ""),
+			Issuer:       config.GetEnv(prefix+"ISSUER", ""),
+			ClientID:     config.GetEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: config.GetEnv(prefix+"CLIENT_SECRET", ""),
+			Scopes:       strings.Fields(config.GetEnv(prefix+"SCOPES", "")),
+			RedirectURL:  config.GetEnv(prefix+"REDIRECT_URL", ""),
+		}
+		if cfg.Issuer == "" || cfg.ClientID == "" {
+			log.Printf("⚠️ 外部身分提供者 %s 缺少 %sISSUER 或 %sCLIENT_ID，已略過", name, prefix, prefix)
+			continue
+		}
+		providers = append(providers, NewIdentityProvider(cfg))
+	}
+	return providers
+}
+
+// Name 路由使用的名稱
+func (p *IdentityProvider) Name() string {
+	return p.cfg.Name
+}
+
+// DisplayName 顯示名稱
+func (p *IdentityProvider) DisplayName() string {
+	return p.cfg.DisplayName
+}
+
+// AuthCodeURL 組出導向 IdP 登入頁的網址
+func (p *IdentityProvider) AuthCodeURL(ctx context.Context, state string, nonce string, codeChallenge string) (string, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	u, err := url.Parse(meta.AuthorizationEndpoint)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", p.cfg.ClientID)
+	q.Set("redirect_uri", p.cfg.RedirectURL)
+	q.Set("scope", strings.Join(p.cfg.Scopes, " "))
+	q.Set("state", state)
+	q.Set("nonce", nonce)
+	q.Set("code_challenge", codeChallenge)
+	q.Set("code_challenge_method", "S256")
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// Exchange 以授權碼向 IdP 換取 ID token 並驗證
+func (p *IdentityProvider) Exchange(ctx context.Context, code string, codeVerifier string, nonce string) (*externalClaims, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"code_verifier": {codeVerifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	// client_secret_basic（RFC 6749 2.3.1：帳密需先做 form 編碼）
+	req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("%s token 回應格式錯誤，狀態碼: %d", p.cfg.Name, resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s token 請求失敗：%s %s", p.cfg.Name, body.Error, body.ErrorDescription)
+	}
+	if body.IDToken == "" {
+		return nil, fmt.Errorf("%s 未回傳 id_token，請確認 scope 包含 openid", p.cfg.Name)
+	}
+	return p.verifyIDToken(meta, body.IDToken, nonce)
+}
+
+// verifyIDToken 驗證 ID token 並取出帳號資料
+func (p *IdentityProvider) verifyIDToken(meta *providerMetadata, raw string, nonce string) (*externalClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, p.jwks.Keyfunc,
+		jwt.WithValidMethods([]string{"RS256", "EdDSA"}),
+		jwt.WithIssuer(meta.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(30*time.Second),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%s id_token 驗證失敗：%w", p.cfg.Name, err)
+	}
+	if got, _ := claims["nonce"].(string); got == "" || got != nonce {
+		return nil, fmt.Errorf("%s id_token nonce 不符", p.cfg.Name)
+	}
+
+	result := &externalClaims{}
+	result.Subject, _ = claims["sub"].(string)
+	if result.Subject == "" {
+		return nil, fmt.Errorf("%s id_token 缺少 sub", p.cfg.Name)
+	}
+	email, _ := claims["email"].(string)
+	result.Email = strings.ToLower(strings.TrimSpace(email))
+	// 部分 IdP 會把 email_verified 放成字串
+	switch v := claims["email_verified"].(type) {
+	case bool:
+		result.EmailVerified = v
+	case string:
+		result.EmailVerified = v == "true"
+	}
+	if name, _ := claims["name"].(string); name != "" {
+		result.Name = name
+	} else {
+		result.Name, _ = claims["preferred_username"].(string)
+	}
+	return result, nil
+}
+
+// discover 取得並快取 discovery 文件；失敗時不快取，下次請求會重試
+func (p *IdentityProvider) discover(ctx context.Context) (*providerMetadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.cfg.Issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s discovery 請求失敗，狀態碼: %d", p.cfg.Name, resp.StatusCode)
+	}
+
+	var meta providerMetadata
+	if err := json.NewDecoder(resp.Body).Decode(&meta); err != nil {
+		return nil, err
+	}
+	// OIDC Discovery 4.3：文件中的 issuer 必須與設定的 issuer 相同
+	if strings.TrimRight(meta.Issuer, "/") != p.cfg.Issuer {
+		return nil, fmt.Errorf("%s discovery issuer 不符：%s", p.cfg.Name, meta.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, errors.New(p.cfg.Name + " discovery 文件缺少必要端點")
+	}
+
+	p.metadata = &meta
+	p.jwks = utils.NewJWKSClient(meta.JWKSURI, idpJWKSTTL)
+	return p.metadata, nil
+}
@@ -0,0 +1,229 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"micro-golang/internal/audit"
+	"micro-golang/internal/blacklist"
+	"micro-golang/internal/config"
+	"micro-golang/internal/models"
+	"micro-golang/internal/rbac"
+	"micro-golang/internal/session"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/**
+ * @File: external_login.go
+ * @Description:
+ *
+ * 以外部身分提供者登入
+ * 已連結的外部帳號直接登入；第一次登入時以 IdP 驗證過的 Email 連結既有帳號，沒有則自動建立一般使用者。
+ * 登入流程的 state / nonce / PKCE verifier 存在 Redis，10 分鐘內只能使用一次。
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/21 上午11:00
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+const (
+	externalStatePrefix = "external_login:" // external_login:<state hash> → externalLoginState JSON
+	externalStateTTL    = 10 * time.Minute
+)
+
+var (
+	ErrUnknownIdentityProvider = errors.New("unknown identity provider")
+	ErrExternalLoginState      = errors.New("external login state invalid or expired")
+	ErrExternalEmailUnverified = errors.New("external identity has no verified email")
+)
+
+// externalLoginState 導向 IdP 前產生、callback 時取回的登入流程資料
+type externalLoginState struct {
+	Provider string `json:"provider"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	// CookieMode callback 是瀏覽器導向、無法帶 X-Auth-Mode，改在流程開始時記錄
+	CookieMode bool `json:"cookie_mode,omitempty"`
+}
+
+// IdentityProviders 已設定的外部身分提供者
+func (s *Service) IdentityProviders() []*IdentityProvider {
+	return s.idps
+}
+
+// BeginExternalLogin 產生登入流程的 state，回傳 IdP 登入頁網址與 state；cookieMode 為登入完成後是否以 cookie 保存 token
+func (s *Service) BeginExternalLogin(ctx context.Context, providerName string, cookieMode bool) (string, string, error) {
+	provider, err := s.identityProvider(providerName)
+	if err != nil {
+		return "", "", err
+	}
+
+	state, stateHash := newOpaqueToken()
+	nonce, _ := newOpaqueToken()
+	verifier, _ := newOpaqueToken()
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, pkceChallenge(verifier))
+	if err != nil {
+		return "", "", err
+	}
+
+	data, _ := json.Marshal(externalLoginState{Provider: provider.Name(), Nonce: nonce, Verifier: verifier, CookieMode: cookieMode})
+	if err := config.RDB.Set(ctx, externalStatePrefix+stateHash, data, externalStateTTL).Err(); err != nil {
+		return "", "", err
+	}
+	return authURL, state, nil
+}
+
+// CompleteExternalLogin 兌換 IdP 的授權碼，回傳對應（或新建立）的本地使用者，以及流程開始時指定的 cookie 登入模式
+func (s *Service) CompleteExternalLogin(ctx context.Context, providerName string, state string, code string, clientIP string) (*models.User, bool, error) {
+	provider, err := s.identityProvider(providerName)
+	if err != nil {
+		return nil, false, err
+	}
+
+	raw, err := config.RDB.GetDel(ctx, externalStatePrefix+hashToken(state)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, ErrExternalLoginState
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	var data externalLoginState
+	if err := json.Unmarshal(raw, &data); err != nil || data.Provider != provider.Name() {
+		return nil, false, ErrExternalLoginState
+	}
+
+	claims, err := provider.Exchange(ctx, code, data.Verifier, data.Nonce)
+	if err != nil {
+		return nil, false, err
+	}
+	user, err := s.linkExternalIdentity(ctx, provider.Name(), *claims, clientIP)
+	return user, data.CookieMode, err
+}
+
+// linkExternalIdentity 找出外部帳號對應的本地使用者，第一次登入時連結或建立帳號
+func (s *Service) linkExternalIdentity(ctx context.Context, providerName string, claims externalClaims, clientIP string) (*models.User, error) {
+	var user models.User
+	err := config.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var identity models.UserIdentity
+		err := tx.Where("provider = ? AND subject = ?", providerName, claims.Subject).First(&identity).Error
+		switch {
+		case err == nil:
+			if err := tx.First(&user, identity.UserID).Error; err != nil {
+				return err
+			}
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			// 沒有經 IdP 驗證的 Email 不可連結既有帳號，否則任何人都能冒用他人 Email 登入
+			if claims.Email == "" || !claims.EmailVerified {
+				return ErrExternalEmailUnverified
+			}
+			linked := true
+			err := tx.Where("email = ?", claims.Email).First(&user).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				if user, err = provisionExternalUser(tx, claims); err != nil {
+					return err
+				}
+				linked = false
+			} else if err != nil {
+				return err
+			}
+
+			// 既有帳號的 Email 尚未驗證時，密碼等登入方式可能是別人搶先用這個 Email 註冊時設定的，
+			// 連結前一併清除並撤銷所有登入，帳號改由 IdP 驗證過的擁有者使用
+			reset := linked && user.EmailVerifiedAt == nil
+			if reset {
+				if err := claimUnverifiedAccount(ctx, tx, &user); err != nil {
+					return err
+				}
+			}
+
+			identity = models.UserIdentity{UserID: user.ID, Provider: providerName, Subject: claims.Subject}
+			if err := tx.Create(&identity).Error; err != nil {
+				return err
+			}
+			// 連結到既有帳號屬於安全相關事件，留下稽核紀錄
+			if linked {
+				detail := map[string]interface{}{"provider": providerName, "subject": identity.Subject}
+				if reset {
+					detail["credentials_reset"] = true
+				}
+				if err := audit.Record(tx, audit.Entry{
+					Actor:      audit.Actor{ID: user.ID, Role: user.Role, IP: clientIP},
+					Action:     audit.ActionIdentityLink,
+					TargetType: "user",
+					TargetID:   strconv.FormatUint(uint64(user.ID), 10),
+					Detail:     detail,
+				}); err != nil {
+					return err
+				}
+			}
+		default:
+			return err
+		}
+
+		return tx.Model(&identity).Updates(map[string]interface{}{
+			"email":         claims.Email,
+			"last_login_at": time.Now(),
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// claimUnverifiedAccount 清除 Email 未驗證帳號的密碼與兩步驟驗證並標記為已驗證，撤銷之前發出的所有 token
+// Redis 撤銷失敗時回傳錯誤讓整個交易回滾，不會留下已連結但舊密碼仍可登入的帳號
+func claimUnverifiedAccount(ctx context.Context, tx *gorm.DB, user *models.User) error {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"password":            "",
+		"totp_secret":         "",
+		"totp_enabled":        false,
+		"email_verified_at":   now,
+		"password_changed_at": now,
+	}
+	if err := tx.Model(user).Updates(updates).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("user_id = ?", user.ID).Delete(&models.MFARecoveryCode{}).Error; err != nil {
+		return err
+	}
+	user.Password, user.TOTPSecret, user.TOTPEnabled, user.EmailVerifiedAt = "", "", false, &now
+	if err := blacklist.RevokeUserTokensBefore(ctx, user.ID, now); err != nil {
+		return err
+	}
+	_, err := session.RevokeAll(ctx, user.ID)
+	return err
+}
+
+// provisionExternalUser 建立外部登入的新帳號：一般使用者、Email 已驗證、沒有本地密碼（可再透過忘記密碼設定）
+func provisionExternalUser(tx *gorm.DB, claims externalClaims) (models.User, error) {
+	username := claims.Name
+	if username == "" {
+		username, _, _ = strings.Cut(claims.Email, "@")
+	}
+	now := time.Now()
+	user := models.User{
+		Email:           claims.Email,
+		Username:        username,
+		Role:            rbac.RoleUser,
+		IsActive:        true,
+		EmailVerifiedAt: &now,
+	}
+	err := tx.Create(&user).Error
+	return user, err
+}
+
+func (s *Service) identityProvider(name string) (*IdentityProvider, error) {
+	for _, p := range s.idps {
+		if p.Name() == name {
+			return p, nil
+		}
+	}
+	return nil, ErrUnknownIdentityProvider
+}
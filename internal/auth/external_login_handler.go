@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"log"
+	"micro-golang/internal/dto"
+	"micro-golang/internal/middlewares"
+	"micro-golang/internal/utils"
+	"net/http"
+)
+
+/**
+ * @File: external_login_handler.go
+ * @Description:
+ *
+ * 外部身分提供者登入：/auth/external/:provider/login 導向 IdP，IdP 登入後導回 callback 發出 token
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/21 上午11:50
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+const externalStateCookie = "external_login_state"
+
+// ListIdentityProviders 列出可用的外部身分提供者，供前端顯示登入按鈕
+func (h *Handler) ListIdentityProviders(c *gin.Context) {
+	result := make([]dto.IdentityProviderDTO, 0, len(h.authService.IdentityProviders()))
+	for _, p := range h.authService.IdentityProviders() {
+		result = append(result, dto.IdentityProviderDTO{
+			Name:        p.Name(),
+			DisplayName: p.DisplayName(),
+			LoginURL:    "/auth/external/" + p.Name() + "/login",
+		})
+	}
+	utils.ReturnSuccess(c, result)
+}
+
+// ExternalLogin 導向外部 IdP 登入
+// 瀏覽器導向無法帶 X-Auth-Mode，要使用 cookie 登入模式時改帶 ?auth_mode=cookie，記錄在登入流程中由 callback 套用
+func (h *Handler) ExternalLogin(c *gin.Context) {
+	cookieMode := middlewares.WantsCookieSession(c) || middlewares.IsCookieAuthMode(c.Query("auth_mode"))
+	authURL, state, err := h.authService.BeginExternalLogin(c, c.Param("provider"), cookieMode)
+	if err != nil {
+		if errors.Is(err, ErrUnknownIdentityProvider) {
+			utils.ReturnError(c, utils.CodeNotFound, nil, "不支援的登入方式")
+			return
+		}
+		log.Printf("❌ 外部登入初始化失敗 (%s)：%v", c.Param("provider"), err)
+		utils.ReturnError(c, utils.CodeServerError, nil, "暫時無法使用此登入方式")
+		return
+	}
+
+	// state 同時存在 cookie，callback 時比對，避免他人把自己的登入結果塞給使用者（login CSRF）
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(externalStateCookie, state, int(externalStateTTL.Seconds()), "/auth/external", "", isHTTPS(c), true)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// ExternalCallback IdP 登入完成後導回，與一般登入相同：啟用兩步驟驗證時先發 challenge token
+func (h *Handler) ExternalCallback(c *gin.Context) {
+	if errCode := c.Query("error"); errCode != "" {
+		utils.ReturnError(c, utils.CodeUnauthorized, nil, "外部登入已取消或失敗："+errCode)
+		return
+	}
+
+	state := c.Query("state")
+	cookie, _ := c.Cookie(externalStateCookie)
+	c.SetCookie(externalStateCookie, "", -1, "/auth/external", "", isHTTPS(c), true)
+	if state == "" || subtle.ConstantTimeCompare([]byte(cookie), []byte(state)) != 1 {
+		utils.ReturnError(c, utils.CodeBadRequest, nil, "登入流程已過期，請重新登入")
+		return
+	}
+
+	user, cookieMode, err := h.authService.CompleteExternalLogin(c, c.Param("provider"), state, c.Query("code"), c.ClientIP())
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrUnknownIdentityProvider):
+			utils.ReturnError(c, utils.CodeNotFound, nil, "不支援的登入方式")
+		case errors.Is(err, ErrExternalLoginState):
+			utils.ReturnError(c, utils.CodeBadRequest, nil, "登入流程已過期，請重新登入")
+		case errors.Is(err, ErrExternalEmailUnverified):
+			utils.ReturnError(c, utils.CodeEmailNotVerified, nil, "外部帳號沒有已驗證的 Email")
+		default:
+			log.Printf("❌ 外部登入失敗 (%s)：%v", c.Param("provider"), err)
+			utils.ReturnError(c, utils.CodeUnauthorized, nil, "外部登入失敗")
+		}
+		return
+	}
+
+	if !user.IsActive {
+		utils.ReturnError(c, utils.CodeAccountInactive, nil, "帳號已停用")
+		return
+	}
+	if user.TOTPEnabled {
+		h.startMFAChallenge(c, *user)
+		return
+	}
+	h.completeLoginWithMode(c, *user, "Login successful", cookieMode)
+}
@@ -0,0 +1,557 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"micro-golang/internal/audit"
+	"micro-golang/internal/blacklist"
+	"micro-golang/internal/config"
+	"micro-golang/internal/mail"
+	"micro-golang/internal/middlewares"
+	"micro-golang/internal/models"
+	"micro-golang/internal/password"
+	"micro-golang/internal/rbac"
+	"micro-golang/internal/user"
+	"micro-golang/internal/utils"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+/**
+ * @File: external_login_test.go
+ * @Description:
+ *
+ * 外部身分提供者登入的整合測試：以 httptest 架一個假的 OIDC IdP（discovery、JWKS、token 端點），
+ * Redis 使用 miniredis、DB 使用 SQLite，走完 login → IdP 授權 → callback 的完整流程。
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/25 上午10:00
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+const (
+	fakeIdPName         = "fake"
+	fakeIdPClientID     = "local-app"
+	fakeIdPClientSecret = "s3cret"
+	fakeIdPRedirectURL  = "http://localhost:7001/auth/external/fake/callback"
+)
+
+// fakeIdP 假的 OIDC 身分提供者，授權碼由測試直接核發（省略 IdP 的登入畫面）
+type fakeIdP struct {
+	t   *testing.T
+	srv *httptest.Server
+	key *rsa.PrivateKey
+	kid string
+
+	mu    sync.Mutex
+	codes map[string]fakeAuthCode
+	hits  map[string]int
+}
+
+// fakeAuthCode 授權碼對應的 PKCE challenge 與要簽進 ID token 的 claims
+type fakeAuthCode struct {
+	challenge string
+	claims    jwt.MapClaims
+	signKey   *rsa.PrivateKey
+}
+
+func newFakeIdP(t *testing.T) *fakeIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &fakeIdP{t: t, key: key, kid: "fake-key-1", codes: map[string]fakeAuthCode{}, hits: map[string]int{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		idp.hit("discovery")
+		writeJSON(w, http.StatusOK, map[string]string{
+			"issuer":                 idp.srv.URL,
+			"authorization_endpoint": idp.srv.URL + "/authorize",
+			"token_endpoint":         idp.srv.URL + "/token",
+			"jwks_uri":               idp.srv.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		idp.hit("jwks")
+		jwk, err := utils.NewJWK(idp.kid, &idp.key.PublicKey)
+		if err != nil {
+			t.Error(err)
+		}
+		writeJSON(w, http.StatusOK, utils.JWKS{Keys: []utils.JWK{jwk}})
+	})
+	mux.HandleFunc("/token", idp.token)
+	idp.srv = httptest.NewServer(mux)
+	t.Cleanup(idp.srv.Close)
+	return idp
+}
+
+func (idp *fakeIdP) hit(name string) {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	idp.hits[name]++
+}
+
+func (idp *fakeIdP) hitCount(name string) int {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	return idp.hits[name]
+}
+
+// token 授權碼換 ID token：檢查 client 帳密、redirect_uri 與 PKCE verifier
+func (idp *fakeIdP) token(w http.ResponseWriter, r *http.Request) {
+	idp.hit("token")
+	clientID, secret, ok := r.BasicAuth()
+	if !ok || clientID != fakeIdPClientID || secret != fakeIdPClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+	if r.PostFormValue("grant_type") != "authorization_code" || r.PostFormValue("redirect_uri") != fakeIdPRedirectURL {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	idp.mu.Lock()
+	code, found := idp.codes[r.PostFormValue("code")]
+	delete(idp.codes, r.PostFormValue("code"))
+	idp.mu.Unlock()
+	if !found || pkceChallenge(r.PostFormValue("code_verifier")) != code.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	signKey := code.signKey
+	if signKey == nil {
+		signKey = idp.key
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, code.claims)
+	token.Header["kid"] = idp.kid
+	idToken, err := token.SignedString(signKey)
+	if err != nil {
+		idp.t.Error(err)
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"access_token": "idp-access-token", "token_type": "Bearer", "id_token": idToken})
+}
+
+// authorize 模擬使用者在 IdP 登入並同意授權，回傳授權碼與 state
+// mutate 可修改預設的 ID token claims 或授權碼內容，用來產生各種異常情境
+func (idp *fakeIdP) authorize(authURL string, subject string, email string, mutate func(*fakeAuthCode)) (string, string) {
+	idp.t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		idp.t.Fatal(err)
+	}
+	if !strings.HasPrefix(authURL, idp.srv.URL+"/authorize?") {
+		idp.t.Fatalf("authorize URL = %s，應導向假 IdP", authURL)
+	}
+	q := u.Query()
+	for k, want := range map[string]string{
+		"response_type":         "code",
+		"client_id":             fakeIdPClientID,
+		"redirect_uri":          fakeIdPRedirectURL,
+		"code_challenge_method": "S256",
+	} {
+		if got := q.Get(k); got != want {
+			idp.t.Fatalf("authorize %s = %q，預期 %q", k, got, want)
+		}
+	}
+	if q.Get("code_challenge") == "" || q.Get("nonce") == "" || q.Get("state") == "" {
+		idp.t.Fatalf("authorize 缺少 code_challenge / nonce / state：%s", authURL)
+	}
+
+	now := time.Now()
+	code := fakeAuthCode{
+		challenge: q.Get("code_challenge"),
+		claims: jwt.MapClaims{
+			"iss":            idp.srv.URL,
+			"aud":            fakeIdPClientID,
+			"sub":            subject,
+			"email":          email,
+			"email_verified": true,
+			"name":           "extUser01",
+			"nonce":          q.Get("nonce"),
+			"iat":            now.Unix(),
+			"exp":            now.Add(5 * time.Minute).Unix(),
+		},
+	}
+	if mutate != nil {
+		mutate(&code)
+	}
+	value, _ := newOpaqueToken()
+	idp.mu.Lock()
+	idp.codes[value] = code
+	idp.mu.Unlock()
+	return value, q.Get("state")
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// setupExternalLogin 準備 miniredis、SQLite、簽章金鑰與指向假 IdP 的 Handler
+func setupExternalLogin(t *testing.T) (*fakeIdP, *Handler) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.UserIdentity{}, &models.AuditLog{}, &models.MFARecoveryCode{}); err != nil {
+		t.Fatal(err)
+	}
+	ks, err := utils.LoadKeySet(t.TempDir(), "RS256", time.Hour, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldDB, oldRDB, oldKeys := config.DB, config.RDB, utils.SigningKeys()
+	config.DB, config.RDB = db, rdb
+	utils.UseSigningKeys(ks)
+	t.Cleanup(func() {
+		config.DB, config.RDB = oldDB, oldRDB
+		if oldKeys != nil {
+			utils.UseSigningKeys(oldKeys)
+		}
+		_ = rdb.Close()
+	})
+
+	idp := newFakeIdP(t)
+	provider := NewIdentityProvider(IdentityProviderConfig{
+		Name:         fakeIdPName,
+		Issuer:       idp.srv.URL,
+		ClientID:     fakeIdPClientID,
+		ClientSecret: fakeIdPClientSecret,
+		RedirectURL:  fakeIdPRedirectURL,
+	})
+	mailer := mail.NewLogMailer("")
+	h := NewHandler(NewService(mailer, []*IdentityProvider{provider}), user.NewService(db, rdb, mailer))
+	return idp, h
+}
+
+func externalLoginRouter(h *Handler) *gin.Engine {
+	r := gin.New()
+	r.GET("/auth/external/:provider/login", h.ExternalLogin)
+	r.GET("/auth/external/:provider/callback", h.ExternalCallback)
+	return r
+}
+
+// beginLogin 呼叫 /login（query 為額外的查詢參數），回傳導向 IdP 的網址與 state cookie
+func beginLogin(t *testing.T, r *gin.Engine, query string) (string, *http.Cookie) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/external/"+fakeIdPName+"/login"+query, nil))
+	if w.Code != http.StatusFound {
+		t.Fatalf("login status = %d，body = %s", w.Code, w.Body.String())
+	}
+	for _, c := range w.Result().Cookies() {
+		if c.Name == externalStateCookie {
+			return w.Header().Get("Location"), c
+		}
+	}
+	t.Fatal("login 沒有設定 state cookie")
+	return "", nil
+}
+
+// callback 帶著 state cookie 呼叫 /callback，回傳解析後的 JSON 回應
+func callback(t *testing.T, r *gin.Engine, state string, code string, cookie *http.Cookie) utils.JsonResult {
+	t.Helper()
+	return decodeResult(t, callbackResponse(t, r, state, code, cookie))
+}
+
+// callbackResponse 帶著 state cookie 呼叫 /callback，回傳原始回應（需檢查 Set-Cookie 時使用）
+func callbackResponse(t *testing.T, r *gin.Engine, state string, code string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/auth/external/"+fakeIdPName+"/callback?"+url.Values{
+		"state": {state},
+		"code":  {code},
+	}.Encode(), nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func decodeResult(t *testing.T, w *httptest.ResponseRecorder) utils.JsonResult {
+	t.Helper()
+	var result utils.JsonResult
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("callback 回應不是 JSON：%s", w.Body.String())
+	}
+	return result
+}
+
+func countRows(t *testing.T, model interface{}, query string, args ...interface{}) int64 {
+	t.Helper()
+	var n int64
+	if err := config.DB.Model(model).Where(query, args...).Count(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestExternalLoginProvisionsNewUser(t *testing.T) {
+	idp, h := setupExternalLogin(t)
+	r := externalLoginRouter(h)
+
+	authURL, cookie := beginLogin(t, r, "")
+	if idp.hitCount("discovery") != 1 {
+		t.Fatalf("discovery 請求次數 = %d，預期 1", idp.hitCount("discovery"))
+	}
+	code, state := idp.authorize(authURL, "sub-new", "New.User@Example.com", nil)
+
+	result := callback(t, r, state, code, cookie)
+	if result.StatusCode != utils.Success {
+		t.Fatalf("callback status_code = %s，msg_detail = %s", result.StatusCode, result.MsgDetail)
+	}
+	data, _ := result.Data.(map[string]interface{})
+	if data["access_token"] == "" || data["refresh_token"] == "" {
+		t.Fatalf("callback 未回傳 token：%v", result.Data)
+	}
+	if idp.hitCount("token") != 1 || idp.hitCount("jwks") == 0 {
+		t.Fatalf("token / jwks 請求次數 = %d / %d", idp.hitCount("token"), idp.hitCount("jwks"))
+	}
+
+	var created models.User
+	if err := config.DB.Where("email = ?", "new.user@example.com").First(&created).Error; err != nil {
+		t.Fatalf("未自動建立使用者：%v", err)
+	}
+	if created.Role != rbac.RoleUser || created.EmailVerifiedAt == nil || created.Password != "" {
+		t.Fatalf("新帳號 role = %s、已驗證 = %v、有密碼 = %v", created.Role, created.EmailVerifiedAt != nil, created.Password != "")
+	}
+	if n := countRows(t, &models.UserIdentity{}, "provider = ? AND subject = ? AND user_id = ?", fakeIdPName, "sub-new", created.ID); n != 1 {
+		t.Fatalf("外部帳號連結筆數 = %d，預期 1", n)
+	}
+}
+
+func TestExternalLoginLinksExistingUserByVerifiedEmail(t *testing.T) {
+	idp, h := setupExternalLogin(t)
+	ctx := context.Background()
+	now := time.Now()
+	existing := models.User{Email: "member@example.com", Username: "member01", Role: rbac.RoleUser, IsActive: true, EmailVerifiedAt: &now}
+	if err := config.DB.Create(&existing).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// 第一次登入：以 Email 連結既有帳號並寫入稽核紀錄
+	for i := 0; i < 2; i++ {
+		authURL, _, err := h.authService.BeginExternalLogin(ctx, fakeIdPName, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		code, state := idp.authorize(authURL, "sub-member", "member@example.com", nil)
+		got, _, err := h.authService.CompleteExternalLogin(ctx, fakeIdPName, state, code, "203.0.113.7")
+		if err != nil {
+			t.Fatalf("第 %d 次登入失敗：%v", i+1, err)
+		}
+		if got.ID != existing.ID {
+			t.Fatalf("第 %d 次登入使用者 = %d，預期連結到既有帳號 %d", i+1, got.ID, existing.ID)
+		}
+	}
+
+	// 第二次登入直接以 provider + sub 找到帳號，不會重複連結或建立帳號
+	if n := countRows(t, &models.UserIdentity{}, "user_id = ?", existing.ID); n != 1 {
+		t.Fatalf("外部帳號連結筆數 = %d，預期 1", n)
+	}
+	if n := countRows(t, &models.User{}, "1 = 1"); n != 1 {
+		t.Fatalf("使用者筆數 = %d，不應建立新帳號", n)
+	}
+	if n := countRows(t, &models.AuditLog{}, "action = ? AND target_id = ?", audit.ActionIdentityLink, "1"); n != 1 {
+		t.Fatalf("連結帳號稽核紀錄筆數 = %d，預期 1", n)
+	}
+}
+
+func TestExternalLoginClaimsUnverifiedAccount(t *testing.T) {
+	idp, h := setupExternalLogin(t)
+	ctx := context.Background()
+
+	// 攻擊者搶先以受害者的 Email 註冊（尚未驗證），密碼由攻擊者設定
+	hashed, err := password.Hash("Attacker-Pass1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	squatted := models.User{Email: "victim@example.com", Username: "squatter", Password: hashed, Role: rbac.RoleUser, IsActive: true}
+	if err := config.DB.Create(&squatted).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	authURL, _, err := h.authService.BeginExternalLogin(ctx, fakeIdPName, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, state := idp.authorize(authURL, "sub-victim", "victim@example.com", nil)
+	got, _, err := h.authService.CompleteExternalLogin(ctx, fakeIdPName, state, code, "203.0.113.7")
+	if err != nil {
+		t.Fatalf("外部登入失敗：%v", err)
+	}
+	if got.ID != squatted.ID || got.Password != "" || got.EmailVerifiedAt == nil {
+		t.Fatalf("回傳的使用者 id = %d、有密碼 = %v、已驗證 = %v", got.ID, got.Password != "", got.EmailVerifiedAt != nil)
+	}
+
+	var stored models.User
+	if err := config.DB.First(&stored, squatted.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.Password != "" || stored.EmailVerifiedAt == nil || stored.PasswordChangedAt == nil {
+		t.Fatalf("連結後有密碼 = %v、已驗證 = %v、已記錄變更時間 = %v", stored.Password != "", stored.EmailVerifiedAt != nil, stored.PasswordChangedAt != nil)
+	}
+	if _, err := h.authService.Authenticate(ctx, "victim@example.com", "Attacker-Pass1", "198.51.100.1"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("攻擊者的密碼仍可登入：%v", err)
+	}
+	if blacklist.UserTokensRevokedBefore(ctx, squatted.ID) == 0 {
+		t.Fatal("未撤銷連結前發出的 token")
+	}
+	if n := countRows(t, &models.AuditLog{}, "action = ? AND detail LIKE ?", audit.ActionIdentityLink, "%credentials_reset%"); n != 1 {
+		t.Fatalf("清除登入方式的稽核紀錄筆數 = %d，預期 1", n)
+	}
+}
+
+func TestExternalLoginRejectsUnverifiedEmail(t *testing.T) {
+	idp, h := setupExternalLogin(t)
+	r := externalLoginRouter(h)
+	now := time.Now()
+	if err := config.DB.Create(&models.User{Email: "victim@example.com", Username: "victim01", Role: rbac.RoleUser, IsActive: true, EmailVerifiedAt: &now}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	for _, email := range []string{"victim@example.com", "someone@example.com"} {
+		authURL, cookie := beginLogin(t, r, "")
+		code, state := idp.authorize(authURL, "sub-"+email, email, func(c *fakeAuthCode) {
+			c.claims["email_verified"] = false
+		})
+		result := callback(t, r, state, code, cookie)
+		if result.StatusCode != utils.CodeEmailNotVerified.StatusCode {
+			t.Fatalf("%s：status_code = %s，預期 %s", email, result.StatusCode, utils.CodeEmailNotVerified.StatusCode)
+		}
+	}
+	if n := countRows(t, &models.UserIdentity{}, "1 = 1"); n != 0 {
+		t.Fatalf("外部帳號連結筆數 = %d，未驗證的 Email 不可連結", n)
+	}
+	if n := countRows(t, &models.User{}, "1 = 1"); n != 1 {
+		t.Fatalf("使用者筆數 = %d，未驗證的 Email 不可建立帳號", n)
+	}
+}
+
+func TestExternalLoginRejectsInvalidIDToken(t *testing.T) {
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name   string
+		mutate func(*fakeAuthCode)
+	}{
+		{"簽章不符", func(c *fakeAuthCode) { c.signKey = otherKey }},
+		{"iss 不符", func(c *fakeAuthCode) { c.claims["iss"] = "https://evil.example.com" }},
+		{"aud 不符", func(c *fakeAuthCode) { c.claims["aud"] = "another-app" }},
+		{"已過期", func(c *fakeAuthCode) { c.claims["exp"] = time.Now().Add(-time.Hour).Unix() }},
+		{"缺少 exp", func(c *fakeAuthCode) { delete(c.claims, "exp") }},
+		{"nonce 不符", func(c *fakeAuthCode) { c.claims["nonce"] = "replayed-nonce" }},
+		{"缺少 nonce", func(c *fakeAuthCode) { delete(c.claims, "nonce") }},
+		{"PKCE verifier 不符", func(c *fakeAuthCode) { c.challenge = pkceChallenge("another-verifier") }},
+	}
+
+	idp, h := setupExternalLogin(t)
+	ctx := context.Background()
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			authURL, _, err := h.authService.BeginExternalLogin(ctx, fakeIdPName, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			code, state := idp.authorize(authURL, "sub-bad", "bad@example.com", tc.mutate)
+			if _, _, err := h.authService.CompleteExternalLogin(ctx, fakeIdPName, state, code, "203.0.113.7"); err == nil {
+				t.Fatal("應拒絕登入")
+			}
+		})
+	}
+	if n := countRows(t, &models.User{}, "1 = 1"); n != 0 {
+		t.Fatalf("使用者筆數 = %d，驗證失敗不可建立帳號", n)
+	}
+}
+
+func TestExternalLoginCookieMode(t *testing.T) {
+	idp, h := setupExternalLogin(t)
+	r := externalLoginRouter(h)
+
+	// callback 是瀏覽器導向、無法帶 X-Auth-Mode，cookie 模式在 /login 指定後記錄在登入流程中
+	authURL, cookie := beginLogin(t, r, "?auth_mode=cookie")
+	code, state := idp.authorize(authURL, "sub-cookie", "cookie.user@example.com", nil)
+
+	w := callbackResponse(t, r, state, code, cookie)
+	result := decodeResult(t, w)
+	if result.StatusCode != utils.Success {
+		t.Fatalf("callback status_code = %s，msg_detail = %s", result.StatusCode, result.MsgDetail)
+	}
+	data, _ := result.Data.(map[string]interface{})
+	csrf, _ := data["csrf_token"].(string)
+	if data["access_token"] != nil || data["refresh_token"] != nil || csrf == "" {
+		t.Fatalf("cookie 模式的回應不應含 token：%v", result.Data)
+	}
+	set := map[string]bool{}
+	for _, c := range w.Result().Cookies() {
+		set[c.Name] = c.Value != ""
+	}
+	if !set[middlewares.AccessTokenCookie] || !set[middlewares.RefreshTokenCookie] || !set[middlewares.CSRFCookie] {
+		t.Fatalf("cookie 模式未寫入 session cookie：%v", set)
+	}
+}
+
+func TestExternalLoginRejectsStateMismatch(t *testing.T) {
+	idp, h := setupExternalLogin(t)
+	r := externalLoginRouter(h)
+
+	// cookie 與 query 的 state 不同（login CSRF：把別人的登入結果塞給使用者）
+	authURL, _ := beginLogin(t, r, "")
+	code, state := idp.authorize(authURL, "sub-csrf", "csrf@example.com", nil)
+	_, otherCookie := beginLogin(t, r, "")
+	if result := callback(t, r, state, code, otherCookie); result.StatusCode != utils.CodeBadRequest.StatusCode {
+		t.Fatalf("state 與 cookie 不符：status_code = %s，預期 %s", result.StatusCode, utils.CodeBadRequest.StatusCode)
+	}
+	if result := callback(t, r, state, code, nil); result.StatusCode != utils.CodeBadRequest.StatusCode {
+		t.Fatalf("沒有 state cookie：status_code = %s，預期 %s", result.StatusCode, utils.CodeBadRequest.StatusCode)
+	}
+
+	// 不是由本服務產生的 state，或已使用過的 state
+	ctx := context.Background()
+	if _, _, err := h.authService.CompleteExternalLogin(ctx, fakeIdPName, "forged-state", code, "203.0.113.7"); !errors.Is(err, ErrExternalLoginState) {
+		t.Fatalf("偽造的 state：err = %v，預期 ErrExternalLoginState", err)
+	}
+	authURL, _, err := h.authService.BeginExternalLogin(ctx, fakeIdPName, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, state = idp.authorize(authURL, "sub-reuse", "reuse@example.com", nil)
+	if _, _, err := h.authService.CompleteExternalLogin(ctx, fakeIdPName, state, code, "203.0.113.7"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := h.authService.CompleteExternalLogin(ctx, fakeIdPName, state, code, "203.0.113.7"); !errors.Is(err, ErrExternalLoginState) {
+		t.Fatalf("重複使用的 state：err = %v，預期 ErrExternalLoginState", err)
+	}
+	if idp.hitCount("token") != 1 {
+		t.Fatalf("token 請求次數 = %d，state 驗證失敗時不應向 IdP 兌換授權碼", idp.hitCount("token"))
+	}
+}
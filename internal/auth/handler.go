@@ -6,12 +6,17 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/bcrypt"
+	"log"
 	"micro-golang/internal/config"
 	"micro-golang/internal/dto"
+	"micro-golang/internal/middlewares"
 	"micro-golang/internal/models"
+	"micro-golang/internal/session"
+	"micro-golang/internal/tokens"
+	"micro-golang/internal/user"
 	"micro-golang/internal/utils"
 	"net/http"
+	"strconv"
 	"time"
 )
 
@@ -27,14 +32,16 @@ import (
 
 // Handler 負責處理認證相關的 HTTP 請求
 type Handler struct {
-	authService Service // 注入 AuthService
+	authService *Service      // 注入 AuthService
+	userService *user.Service // 確認變更 Email 等與 usersvc 共用的使用者操作
 }
 
 // NewHandler 是一個建構函式，用於建立 AuthHandler 的實例
 // AuthService 應該在 main.go 或設定路由的地方被初始化並傳入
-func NewHandler(authService Service) *Handler {
+func NewHandler(authService *Service, userService *user.Service) *Handler {
 	return &Handler{
 		authService: authService,
+		userService: userService,
 	}
 }
 
@@ -60,34 +67,63 @@ func (h *Handler) Register(c *gin.Context) {
 // Login 會員登入
 func (h *Handler) Login(c *gin.Context) {
 	var input dto.UserLoginDTO
-	var dbUser models.User
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON"})
 		return
 	}
 
-	// 找出該 Email 的使用者
-	result := config.DB.Where("email = ?", input.Email).First(&dbUser)
-	if result.Error != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Email does not exist"})
+	// 驗證帳密（含暴力破解鎖定），Email 不存在與密碼錯誤回應一致
+	user, err := h.authService.Authenticate(c, input.Email, input.Password, c.ClientIP())
+	if err != nil {
+		var locked *LoginLockedError
+		switch {
+		case errors.As(err, &locked):
+			respondLoginLocked(c, locked.RetryAfter)
+		case errors.Is(err, ErrInvalidCredentials):
+			utils.ReturnError(c, utils.CodeInvalidCredentials, nil, "Email 或密碼錯誤")
+		default:
+			utils.ReturnError(c, utils.CodeServerError, nil, "登入失敗")
+		}
 		return
 	}
+	dbUser := *user
 
-	// 檢查密碼
-	err := bcrypt.CompareHashAndPassword([]byte(dbUser.Password), []byte(input.Password))
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid Password"})
+	// 帳號狀態檢查：停用或尚未完成 Email 驗證都不可登入
+	if !dbUser.IsActive {
+		utils.ReturnError(c, utils.CodeAccountInactive, nil, "帳號已停用")
+		return
+	}
+	if dbUser.EmailVerifiedAt == nil {
+		utils.ReturnError(c, utils.CodeEmailNotVerified, nil, "Email 尚未驗證，請至信箱點擊驗證連結")
 		return
 	}
 
-	// 產生 JWT token
-	accessToken, refreshToken, err := utils.GenerateJWT(dbUser.Email, dbUser.ID, "User")
+	// 已啟用兩步驟驗證：密碼正確後只發 challenge token，需再搭配 TOTP / 復原碼換取正式 token
+	if dbUser.TOTPEnabled {
+		h.startMFAChallenge(c, dbUser)
+		return
+	}
+
+	h.completeLogin(c, dbUser, "Login successful")
+}
+
+// completeLogin 發出 token、建立 session 並快取使用者資料（密碼登入與兩步驟驗證共用）
+func (h *Handler) completeLogin(c *gin.Context, dbUser models.User, msg string) {
+	h.completeLoginWithMode(c, dbUser, msg, middlewares.WantsCookieSession(c))
+}
+
+// completeLoginWithMode 同 completeLogin，由呼叫端指定是否使用 cookie 登入模式（外部登入由流程開始時的設定決定）
+func (h *Handler) completeLoginWithMode(c *gin.Context, dbUser models.User, msg string, cookieMode bool) {
+	// 產生 JWT token，每次登入都開一個新的 session（即 refresh token 家族）
+	accessToken, refreshToken, err := startSession(c, dbUser, nil)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		log.Printf("❌ 產生 token 失敗 (user %d)：%v", dbUser.ID, err)
+		utils.ReturnError(c, utils.CodeServerError, nil, "產生 token 失敗")
+		return
 	}
 
-	// 快取使用者資料
-	cacheKey := "user:" + dbUser.Email
+	// 快取使用者資料（與 usersvc 共用，以不會變動的 ID 為 key）
+	cacheKey := "user:id:" + strconv.FormatUint(uint64(dbUser.ID), 10)
 
 	// 使dbUser 變成 JSON 標準格式 ，safeUser 存取需要的資訊進去
 	safeUser := dto.UserDTO{
@@ -97,6 +133,8 @@ func (h *Handler) Login(c *gin.Context) {
 		Role:         dbUser.Role,
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
+		// 管理者角色必須啟用兩步驟驗證，尚未設定時 token 只能用於 /auth/mfa/totp/*，前端需導去設定頁
+		MFASetupRequired: requiresMFA(dbUser.Role) && !dbUser.TOTPEnabled,
 	}
 	var responseDTO dto.UserLoginResponseDTO
 	bytes, _ := json.Marshal(safeUser)
@@ -112,63 +150,83 @@ func (h *Handler) Login(c *gin.Context) {
 	userBytes, _ := json.Marshal(responseDTO)
 	config.RDB.Set(config.Ctx, cacheKey, userBytes, 10*time.Minute)
 
-	utils.ReturnSuccess(c, safeUser, "Login successful")
-}
-
-// RefreshToken 重新獲取 Token
-func (h *Handler) RefreshToken(c *gin.Context) {
-	// 從 JSON 或 localStorage 帶進來
-	var input struct {
-		RefreshToken string `json:"refresh_token"`
+	if cookieMode {
+		if err := useSessionCookies(c, &safeUser); err != nil {
+			log.Printf("❌ 寫入登入 cookie 失敗 (user %d)：%v", dbUser.ID, err)
+			utils.ReturnError(c, utils.CodeServerError, nil, "產生 token 失敗")
+			return
+		}
 	}
-	if err := c.ShouldBindJSON(&input); err != nil || input.RefreshToken == "" {
-		utils.ReturnError(c, utils.CodeParamInvalid, nil, "請提供 refresh_token")
-		return
+	if safeUser.MFASetupRequired {
+		msg = "管理者帳號必須先啟用兩步驟驗證，目前的 token 只能用於設定 TOTP"
 	}
+	utils.ReturnSuccess(c, safeUser, msg)
+}
 
-	// 解析 token
-	claims := jwt.MapClaims{}
-	token, err := jwt.ParseWithClaims(input.RefreshToken, claims, func(token *jwt.Token) (interface{}, error) {
-		return utils.JwtKey, nil
-	})
-
-	// 檢查Redis 是否存在黑名單
-	isBlacklisted, _ := config.RDB.Exists(config.Ctx, "blacklist:refresh_token:"+input.RefreshToken).Result()
-	if isBlacklisted == 1 {
-		utils.ReturnError(c, utils.CodeUnauthorized, nil, "refresh_token 已失效，請重新登入")
-		return
+// useSessionCookies Cookie 登入模式：token 改寫入 HttpOnly cookie，回應只帶 CSRF token
+func useSessionCookies(c *gin.Context, safeUser *dto.UserDTO) error {
+	csrf, err := middlewares.SetSessionCookies(c, safeUser.AccessToken, safeUser.RefreshToken)
+	if err != nil {
+		return err
 	}
+	safeUser.AccessToken, safeUser.RefreshToken, safeUser.CSRFToken = "", "", csrf
+	return nil
+}
 
-	// 驗證 token 是否有效 & 是 refresh token
-	if err != nil || !token.Valid || claims["token_type"] != "refresh" {
-		utils.ReturnError(c, utils.CodeUnauthorized, nil, "refresh_token 無效或過期")
-		return
+// startSession 開一個新的 session（即 refresh token 家族）並發出第一組 token
+func startSession(c *gin.Context, user models.User, grant jwt.MapClaims) (string, string, error) {
+	sessionID := utils.NewTokenID()
+	accessToken, refreshToken, err := issueTokens(c, user, sessionID, grant)
+	if err != nil {
+		return "", "", err
 	}
-
-	email, ok := claims["email"].(string)
-	if !ok {
-		utils.ReturnError(c, utils.CodeUnauthorized, nil, "Token 內容無效")
-		return
+	if err := session.Create(c, user.ID, sessionID, c.Request.UserAgent(), c.ClientIP()); err != nil {
+		return "", "", err
 	}
+	return accessToken, refreshToken, nil
+}
 
-	// 查詢使用者資料
-	var dbUser models.User
-	result := config.DB.Where("email = ?", email).First(&dbUser)
-	if result.Error != nil {
-		utils.ReturnError(c, utils.CodeUnauthorized, nil, "找不到使用者")
+// RefreshToken 重新獲取 Token
+func (h *Handler) RefreshToken(c *gin.Context) {
+	// 從 JSON 或 localStorage 帶進來；Cookie 登入模式則從 HttpOnly cookie 讀取
+	var input struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	_ = c.ShouldBindJSON(&input)
+	cookieMode := middlewares.WantsCookieSession(c)
+	if input.RefreshToken == "" {
+		if v, err := c.Cookie(middlewares.RefreshTokenCookie); err == nil && v != "" {
+			input.RefreshToken, cookieMode = v, true
+		}
+	}
+	if input.RefreshToken == "" {
+		utils.ReturnError(c, utils.CodeParamInvalid, nil, "請提供 refresh_token")
 		return
 	}
 
-	// 產生新 token
-	newAccessToken, newRefreshToken, err := utils.GenerateJWT(dbUser.Email, dbUser.ID, "User")
+	result, err := refreshTokens(c, input.RefreshToken, "", c.Request.UserAgent(), c.ClientIP())
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, utils.JsonResult{
-			StatusCode: "500",
-			Msg:        "Can't generate access token",
-			MsgDetail:  "無法產生新 token",
-		})
+		switch {
+		case errors.Is(err, ErrRefreshTokenInvalid):
+			utils.ReturnError(c, utils.CodeUnauthorized, nil, "refresh_token 無效或過期")
+		case errors.Is(err, ErrRefreshTokenReused):
+			utils.ReturnError(c, utils.CodeUnauthorized, nil, "refresh_token 已被使用過，為安全起見已撤銷此登入的所有 token，請重新登入")
+		case errors.Is(err, ErrRefreshTokenRevoked):
+			utils.ReturnError(c, utils.CodeUnauthorized, nil, "refresh_token 已失效，請重新登入")
+		case errors.Is(err, ErrAccountInactive):
+			utils.ReturnError(c, utils.CodeAccountInactive, nil, "帳號已停用")
+		case errors.Is(err, ErrSessionRevoked):
+			utils.ReturnError(c, utils.CodeUnauthorized, nil, "此裝置的登入已被登出，請重新登入")
+		default:
+			c.JSON(http.StatusUnauthorized, utils.JsonResult{
+				StatusCode: "500",
+				Msg:        "Can't generate access token",
+				MsgDetail:  "無法產生新 token",
+			})
+		}
 		return
 	}
+	dbUser := result.User
 
 	// 統一回傳 DTO
 	safeUser := dto.UserDTO{
@@ -176,8 +234,14 @@ func (h *Handler) RefreshToken(c *gin.Context) {
 		Email:        dbUser.Email,
 		Username:     dbUser.Username,
 		Role:         dbUser.Role,
-		AccessToken:  newAccessToken,
-		RefreshToken: newRefreshToken,
+		AccessToken:  result.AccessToken,
+		RefreshToken: result.RefreshToken,
+	}
+	if cookieMode {
+		if err := useSessionCookies(c, &safeUser); err != nil {
+			utils.ReturnError(c, utils.CodeServerError, nil, "無法產生新 token")
+			return
+		}
 	}
 	utils.ReturnSuccess(c, safeUser, "Token refreshed successfully")
 }
@@ -187,30 +251,26 @@ func (h *Handler) LogoutHandler(c *gin.Context) {
 
 	var input dto.UserDTO
 	if err := c.ShouldBindJSON(&input); err != nil {
-		utils.ReturnError(c, utils.CodeBadRequest, nil, "格式錯誤")
-		return
-	}
-	// 1️⃣ access_token 放進黑名單
-	accessClaims, err := utils.ParseToken(input.AccessToken)
-	if err == nil {
-		if exp, ok := accessClaims["exp"].(float64); ok {
-			ttl := time.Until(time.Unix(int64(exp), 0))
-			// 若還沒過期則加入黑名單
-			if ttl > 0 {
-				config.RDB.Set(c, "blacklist:access_token:"+input.AccessToken, "1", ttl)
-			}
+		// Cookie 登入模式可以不帶 body，token 從 cookie 讀取
+		if _, cookieErr := c.Cookie(middlewares.RefreshTokenCookie); c.Request.ContentLength > 0 || cookieErr != nil {
+			utils.ReturnError(c, utils.CodeBadRequest, nil, "格式錯誤")
+			return
 		}
 	}
-	// 2️⃣ refresh_token 放進黑名單
-	refreshClaims, err := utils.ParseToken(input.RefreshToken)
-	if err == nil {
-		if exp, ok := refreshClaims["exp"].(float64); ok {
-			ttl := time.Until(time.Unix(int64(exp), 0))
-			// 若還沒過期則加入黑名單
-			if ttl > 0 {
-				config.RDB.Set(c, "blacklist:refresh_token:"+input.RefreshToken, "1", ttl)
-			}
+	if input.AccessToken == "" && input.RefreshToken == "" {
+		input.AccessToken, _ = c.Cookie(middlewares.AccessTokenCookie)
+		input.RefreshToken, _ = c.Cookie(middlewares.RefreshTokenCookie)
+		if input.AccessToken != "" || input.RefreshToken != "" {
+			middlewares.ClearSessionCookies(c)
 		}
 	}
+	// 1️⃣ access_token 放進黑名單（若還沒過期）
+	if accessClaims, err := utils.ParseToken(input.AccessToken); err == nil {
+		_ = revokeToken(c, input.AccessToken, accessClaims, tokens.TypeAccess)
+	}
+	// 2️⃣ refresh_token 放進黑名單，並撤銷此裝置的 session（整個 token 家族）
+	if refreshClaims, err := utils.ParseToken(input.RefreshToken); err == nil {
+		_ = revokeToken(c, input.RefreshToken, refreshClaims, tokens.TypeRefresh)
+	}
 	utils.ReturnSuccess(c, nil, "Logout successful")
 }
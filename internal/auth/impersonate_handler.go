@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"log"
+	"micro-golang/internal/audit"
+	"micro-golang/internal/dto"
+	"micro-golang/internal/utils"
+	"strconv"
+)
+
+/**
+ * @File: impersonate_handler.go
+ * @Description:
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/22 下午4:40
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// Impersonate 管理者代理登入（POST /admin/impersonate/:userId）
+func (h *Handler) Impersonate(c *gin.Context) {
+	targetID, err := strconv.ParseUint(c.Param("userId"), 10, 64)
+	if err != nil {
+		utils.ReturnError(c, utils.CodeParamInvalid, nil, "使用者 ID 格式錯誤")
+		return
+	}
+
+	var req dto.ImpersonateDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		var ve validator.ValidationErrors
+		if errors.As(err, &ve) {
+			utils.ReturnError(c, utils.CodeParamInvalid, utils.ExtractFieldErrorMessages(req, ve), "欄位驗證失敗")
+			return
+		}
+		utils.ReturnError(c, utils.CodeParamInvalid, err.Error())
+		return
+	}
+
+	actor := ImpersonationActor{
+		Actor:     audit.ActorFromContext(c),
+		Email:     c.GetString("email"),
+		SessionID: c.GetString("sessionId"),
+	}
+	token, target, err := h.authService.Impersonate(c, actor, uint(targetID), req.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrImpersonateSelf):
+			utils.ReturnError(c, utils.CodeParamInvalid, nil, "不能代理自己")
+		case errors.Is(err, ErrImpersonateUserMissing):
+			utils.ReturnError(c, utils.CodeNotFound, nil, "找不到使用者或帳號已停用")
+		case errors.Is(err, ErrImpersonateNotAllowed):
+			utils.ReturnError(c, utils.CodeForbidden, nil, "只能代理比自己低階的使用者")
+		default:
+			log.Printf("❌ 代理登入失敗：%v", err)
+			utils.ReturnError(c, utils.CodeServerError, nil, "代理登入失敗")
+		}
+		return
+	}
+
+	utils.ReturnSuccess(c, dto.ImpersonationTokenDTO{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(impersonationTTL.Seconds()),
+		User:        profileOf(*target),
+	}, "Impersonation started")
+}
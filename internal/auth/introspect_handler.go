@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"github.com/gin-gonic/gin"
+	"log"
+	"micro-golang/internal/dto"
+	"micro-golang/internal/middlewares"
+	"micro-golang/internal/tokens"
+	"net/http"
+)
+
+/**
+ * @File: introspect_handler.go
+ * @Description:
+ *
+ * /auth/introspect、/auth/revoke，需以 OAuth client 驗證（HTTP Basic 或表單 client_id / client_secret），
+ * 回應格式依 RFC 7662 / RFC 7009，不使用 utils.JsonResult
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/20 下午4:50
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// Introspect 查詢 token 是否有效及其擁有者，只開放給 confidential client（資源伺服器）
+func (h *Handler) Introspect(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+
+	client, err := h.clientFromRequest(c)
+	if err != nil {
+		oauthTokenError(c, err)
+		return
+	}
+	if client.Public {
+		oauthTokenError(c, oauthError("unauthorized_client", "public client 不可查詢 token"))
+		return
+	}
+	token := c.PostForm("token")
+	if token == "" {
+		oauthTokenError(c, oauthError("invalid_request", "缺少 token"))
+		return
+	}
+
+	// 無效、過期、已撤銷或 ID token 一律只回 active=false，不透露原因；
+	// 管理者尚未設定兩步驟驗證時的 token 只能用於設定 TOTP，對資源伺服器而言也視為無效（與 JWTAuth 一致）
+	claims, tokenType, err := tokens.Verify(c, token)
+	if setup, _ := claims[middlewares.MFASetupClaim].(bool); err != nil || tokenType == tokens.TypeID || setup {
+		c.JSON(http.StatusOK, dto.IntrospectionResponse{Active: false})
+		return
+	}
+	c.JSON(http.StatusOK, introspection(claims, tokenType))
+}
+
+// Revoke 撤銷 token
+// 發給特定 client 的 token 只能由該 client 撤銷；一般登入的 token（沒有 cid）只能由註冊為 first_party 的 client 撤銷（RFC 7009 2.1）
+func (h *Handler) Revoke(c *gin.Context) {
+	client, err := h.clientFromRequest(c)
+	if err != nil {
+		oauthTokenError(c, err)
+		return
+	}
+	token := c.PostForm("token")
+	if token == "" {
+		oauthTokenError(c, oauthError("invalid_request", "缺少 token"))
+		return
+	}
+
+	// 依 RFC 7009，無效或已撤銷的 token 也回 200
+	claims, tokenType, err := tokens.Verify(c, token)
+	if err != nil {
+		c.Status(http.StatusOK)
+		return
+	}
+	if tokenType == tokens.TypeID {
+		oauthTokenError(c, oauthError("unsupported_token_type", "ID token 無法撤銷"))
+		return
+	}
+	cid, _ := claims["cid"].(string)
+	if (cid != "" && cid != client.ClientID) || (cid == "" && !client.FirstParty) {
+		oauthTokenError(c, oauthError("unauthorized_client", "此 token 不是發給這個 client 的"))
+		return
+	}
+
+	if err := revokeToken(c, token, claims, tokenType); err != nil {
+		log.Printf("❌ 撤銷 token 失敗：%v", err)
+		c.JSON(http.StatusServiceUnavailable, dto.OAuthErrorResponse{Error: "temporarily_unavailable"})
+		return
+	}
+	c.Status(http.StatusOK)
+}
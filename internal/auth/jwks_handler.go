@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"github.com/gin-gonic/gin"
+	"micro-golang/internal/utils"
+	"net/http"
+)
+
+/**
+ * @File: jwks_handler.go
+ * @Description:
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/18 下午2:30
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// JWKS 公開目前可用於驗章的公鑰（/.well-known/jwks.json）
+// 依 RFC 7517 直接回傳 JWKS 格式，不包 JsonResult，讓其他服務與第三方工具可直接使用
+func (h *Handler) JWKS(c *gin.Context) {
+	ks := utils.SigningKeys()
+	if ks == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "signing keys not loaded"})
+		return
+	}
+	// 驗章端自己也有快取，這裡給短一點的 max-age 讓新金鑰盡快被看到
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, ks.JWKS())
+}
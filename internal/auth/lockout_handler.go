@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"log"
+	"micro-golang/internal/audit"
+	"micro-golang/internal/config"
+	"micro-golang/internal/dto"
+	"micro-golang/internal/utils"
+	"strconv"
+	"time"
+)
+
+/**
+ * @File: lockout_handler.go
+ * @Description:
+ *
+ * 登入鎖定的回應格式，以及管理者解除鎖定 API（/admin/lockouts）
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/19 下午5:20
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// respondLoginLocked 回傳 429 並帶 Retry-After（秒）
+func respondLoginLocked(c *gin.Context, retryAfter time.Duration) {
+	seconds := int(retryAfter.Round(time.Second) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	utils.ReturnError(c, utils.CodeTooManyRequests, gin.H{"retry_after": seconds},
+		fmt.Sprintf("登入失敗次數過多，請於 %d 秒後再試", seconds))
+}
+
+// UnlockLogin 管理者解除 Email 或 IP 的登入鎖定
+func (h *Handler) UnlockLogin(c *gin.Context) {
+	var req dto.AdminUnlockLoginDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		var ve validator.ValidationErrors
+		if errors.As(err, &ve) {
+			utils.ReturnError(c, utils.CodeParamInvalid, utils.ExtractFieldErrorMessages(req, ve), "欄位驗證失敗")
+			return
+		}
+		utils.ReturnError(c, utils.CodeParamInvalid, err.Error())
+		return
+	}
+	if req.Email == "" && req.IP == "" {
+		utils.ReturnError(c, utils.CodeParamInvalid, nil, "Email 與 IP 至少填一個")
+		return
+	}
+
+	if err := h.authService.UnlockLogin(c, req.Email, req.IP); err != nil {
+		utils.ReturnError(c, utils.CodeServerError, nil, "解除鎖定失敗")
+		return
+	}
+
+	target := req.Email
+	if target == "" {
+		target = req.IP
+	}
+	err := audit.Record(config.DB.WithContext(c), audit.Entry{
+		Actor:      audit.ActorFromContext(c),
+		Action:     audit.ActionLoginUnlock,
+		TargetType: "login_lock",
+		TargetID:   target,
+		Detail:     req,
+	})
+	if err != nil {
+		log.Printf("⚠️ 寫入稽核紀錄失敗：%v", err)
+	}
+	utils.ReturnSuccess(c, nil, "Login unlocked")
+}
@@ -0,0 +1,213 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"micro-golang/internal/config"
+	"micro-golang/internal/models"
+	"micro-golang/internal/password"
+	"strings"
+	"sync"
+	"time"
+)
+
+/**
+ * @File: login_guard.go
+ * @Description:
+ *
+ * 登入暴力破解防護
+ * 以 Redis 分別依 Email 與來源 IP 計算失敗次數，超過門檻後暫時鎖定，
+ * 鎖定時間以指數退避（每多失敗一次加倍），上限為 LOGIN_LOCKOUT_MAX。
+ * Email 不存在時仍會執行一次密碼雜湊比對，回應內容與時間都與密碼錯誤相同，避免被拿來探測帳號。
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/19 下午5:00
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+const (
+	loginFailPrefix = "login_fail:" // login_fail:email:<email>、login_fail:ip:<ip> → 失敗次數
+	loginLockPrefix = "login_lock:" // login_lock:email:<email>、login_lock:ip:<ip> → 鎖定中
+)
+
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// LoginLockedError 登入已被暫時鎖定
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return fmt.Sprintf("login locked, retry after %s", e.RetryAfter)
+}
+
+// lockoutPolicy 鎖定規則，可由環境變數調整
+type lockoutPolicy struct {
+	emailMaxAttempts int64         // LOGIN_MAX_ATTEMPTS
+	ipMaxAttempts    int64         // LOGIN_IP_MAX_ATTEMPTS
+	window           time.Duration // LOGIN_ATTEMPT_WINDOW 失敗次數的計算時間窗
+	baseLockout      time.Duration // LOGIN_LOCKOUT_BASE 第一次鎖定的時間
+	maxLockout       time.Duration // LOGIN_LOCKOUT_MAX 鎖定時間上限
+}
+
+func loadLockoutPolicy() lockoutPolicy {
+	return lockoutPolicy{
+		emailMaxAttempts: int64(config.GetEnvInt("LOGIN_MAX_ATTEMPTS", 5)),
+		ipMaxAttempts:    int64(config.GetEnvInt("LOGIN_IP_MAX_ATTEMPTS", 20)),
+		window:           config.GetEnvDuration("LOGIN_ATTEMPT_WINDOW", 15*time.Minute),
+		baseLockout:      config.GetEnvDuration("LOGIN_LOCKOUT_BASE", time.Minute),
+		maxLockout:       config.GetEnvDuration("LOGIN_LOCKOUT_MAX", time.Hour),
+	}
+}
+
+// lockoutFor 第 n 次失敗（n ≥ max）應鎖定的時間
+func (p lockoutPolicy) lockoutFor(failures int64, max int64) time.Duration {
+	lock := p.baseLockout
+	for i := max; i < failures && lock < p.maxLockout; i++ {
+		lock *= 2
+	}
+	if lock > p.maxLockout {
+		lock = p.maxLockout
+	}
+	return lock
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+// timingDummyHash Email 不存在時用來比對的假雜湊（與新密碼相同演算法），讓回應時間與真的比對密碼一致
+func timingDummyHash() string {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = password.Hash("timing-dummy-password")
+	})
+	return dummyHash
+}
+
+// Authenticate 驗證 Email / 密碼，包含鎖定檢查與失敗計數
+// 失敗時一律回傳 ErrInvalidCredentials 或 *LoginLockedError，不區分帳號是否存在
+// 驗證成功且密碼雜湊不是目前偏好的演算法 / 參數時，順便重新雜湊
+func (s *Service) Authenticate(ctx context.Context, email string, plain string, clientIP string) (*models.User, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	emailKey := "email:" + email
+	ipKey := "ip:" + clientIP
+
+	if wait := lockRemaining(ctx, emailKey, ipKey); wait > 0 {
+		return nil, &LoginLockedError{RetryAfter: wait}
+	}
+
+	var user models.User
+	found := config.DB.WithContext(ctx).Where("email = ?", email).First(&user).Error == nil
+	// 外部登入建立的帳號沒有密碼，與 Email 不存在同樣處理
+	usable := found && user.Password != ""
+	hash := timingDummyHash()
+	if usable {
+		hash = user.Password
+	}
+	if ok, _ := password.Verify(plain, hash); !ok || !usable {
+		if wait := recordLoginFailure(ctx, email, clientIP); wait > 0 {
+			return nil, &LoginLockedError{RetryAfter: wait}
+		}
+		return nil, ErrInvalidCredentials
+	}
+
+	// 已啟用兩步驟驗證時要等第二步通過才清除失敗次數，否則每次重新輸入密碼就能無限嘗試驗證碼
+	if !user.TOTPEnabled {
+		clearLoginFailures(ctx, email)
+	}
+
+	if password.NeedsRehash(user.Password) {
+		rehashPassword(ctx, &user, plain)
+	}
+	return &user, nil
+}
+
+// rehashPassword 以偏好的演算法重新雜湊密碼；失敗只記 log，不影響登入
+// 以舊雜湊值作為條件更新，避免覆蓋同時間被重設的新密碼
+func rehashPassword(ctx context.Context, user *models.User, plain string) {
+	hashed, err := password.Hash(plain)
+	if err != nil {
+		log.Printf("❌ 重新雜湊密碼失敗 (user %d)：%v", user.ID, err)
+		return
+	}
+	err = config.DB.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND password = ?", user.ID, user.Password).
+		Update("password", hashed).Error
+	if err != nil {
+		log.Printf("❌ 更新密碼雜湊失敗 (user %d)：%v", user.ID, err)
+		return
+	}
+	user.Password = hashed
+}
+
+// UnlockLogin 解除 Email 及 / 或 IP 的鎖定並清除失敗次數
+func (s *Service) UnlockLogin(ctx context.Context, email string, clientIP string) error {
+	var keys []string
+	if email != "" {
+		email = strings.ToLower(strings.TrimSpace(email))
+		keys = append(keys, loginFailPrefix+"email:"+email, loginLockPrefix+"email:"+email)
+	}
+	if clientIP != "" {
+		keys = append(keys, loginFailPrefix+"ip:"+clientIP, loginLockPrefix+"ip:"+clientIP)
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return config.RDB.Del(ctx, keys...).Err()
+}
+
+// clearLoginFailures 登入成功只清除該 Email 的失敗次數，IP 的計數保留，避免攻擊者用自己的帳號洗掉
+func clearLoginFailures(ctx context.Context, email string) {
+	config.RDB.Del(ctx, loginFailPrefix+"email:"+strings.ToLower(strings.TrimSpace(email)))
+}
+
+// recordLoginFailure 記錄一次登入失敗（Email 與 IP 各自計數），回傳鎖定時間，0 表示未鎖定
+// 密碼正確但第二步驗證失敗時也要呼叫，避免驗證碼被暴力嘗試
+func recordLoginFailure(ctx context.Context, email string, clientIP string) time.Duration {
+	policy := loadLockoutPolicy()
+	email = strings.ToLower(strings.TrimSpace(email))
+	emailWait := recordFailure(ctx, "email:"+email, policy.emailMaxAttempts, policy)
+	ipWait := recordFailure(ctx, "ip:"+clientIP, policy.ipMaxAttempts, policy)
+	return max(emailWait, ipWait)
+}
+
+// lockRemaining 回傳剩餘的鎖定時間，0 表示未鎖定
+// Redis 發生錯誤時放行（只記 log），避免 Redis 故障導致所有人都無法登入
+func lockRemaining(ctx context.Context, keys ...string) time.Duration {
+	var wait time.Duration
+	for _, k := range keys {
+		ttl, err := config.RDB.PTTL(ctx, loginLockPrefix+k).Result()
+		if err != nil {
+			log.Printf("⚠️ 讀取登入鎖定狀態失敗：%v", err)
+			continue
+		}
+		wait = max(wait, ttl)
+	}
+	return wait
+}
+
+// recordFailure 累加失敗次數，達到門檻時鎖定並回傳鎖定時間
+func recordFailure(ctx context.Context, key string, maxAttempts int64, policy lockoutPolicy) time.Duration {
+	failures, err := config.RDB.Incr(ctx, loginFailPrefix+key).Result()
+	if err != nil {
+		log.Printf("⚠️ 記錄登入失敗次數失敗：%v", err)
+		return 0
+	}
+	if failures < maxAttempts {
+		if failures == 1 {
+			config.RDB.Expire(ctx, loginFailPrefix+key, policy.window)
+		}
+		return 0
+	}
+
+	lock := policy.lockoutFor(failures, maxAttempts)
+	config.RDB.Set(ctx, loginLockPrefix+key, failures, lock)
+	// 鎖定期間與解鎖後一段時間內保留計數，下次失敗才會繼續加倍
+	config.RDB.Expire(ctx, loginFailPrefix+key, lock+policy.window)
+	log.Printf("🔒 登入失敗 %d 次，鎖定 %s %s", failures, key, lock)
+	return lock
+}
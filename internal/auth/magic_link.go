@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"micro-golang/internal/config"
+	"micro-golang/internal/mail"
+	"micro-golang/internal/models"
+	"micro-golang/internal/utils"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/**
+ * @File: magic_link.go
+ * @Description:
+ *
+ * 免密碼登入：寄出有簽章、短時效的一次性登入連結
+ * 連結內的 token 為帶 token_type=magic_link 的 JWT，jti 登記在 Redis，使用後即刪除；
+ * 每個使用者只有最新寄出的一封有效。
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/21 下午2:00
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+const (
+	magicLinkPrefix     = "magic_link:"      // magic_link:<jti> → userId
+	magicLinkUserPrefix = "magic_link:user:" // magic_link:user:<userId> → jti，確保只有最新一封有效
+	magicLinkTTL        = 15 * time.Minute
+	magicLinkTokenType  = "magic_link"
+
+	// 寄送限流：同一 Email 每小時 5 次、同一 IP 每小時 20 次
+	magicLinkEmailLimit = 5
+	magicLinkIPLimit    = 20
+	magicLinkWindow     = time.Hour
+)
+
+var ErrInvalidMagicLink = errors.New("invalid or expired magic link")
+
+// SendMagicLink 寄出登入連結
+// Email 不存在或帳號已停用時直接回傳 nil，不讓呼叫端得知帳號狀態；但仍會計入限流
+func (s *Service) SendMagicLink(ctx context.Context, email string, clientIP string) error {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if ok, err := allow(ctx, "rate:magic_link:email:"+email, magicLinkEmailLimit, magicLinkWindow); err != nil {
+		return err
+	} else if !ok {
+		return ErrTooManyRequests
+	}
+	if ok, err := allow(ctx, "rate:magic_link:ip:"+clientIP, magicLinkIPLimit, magicLinkWindow); err != nil {
+		return err
+	} else if !ok {
+		return ErrTooManyRequests
+	}
+
+	var user models.User
+	if err := config.DB.WithContext(ctx).Where("email = ?", email).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if !user.IsActive {
+		return nil
+	}
+
+	jti := utils.NewTokenID()
+	token, err := utils.SignClaims(jwt.MapClaims{
+		"email":      user.Email,
+		"userId":     user.ID,
+		"token_type": magicLinkTokenType, // JWTAuth 與 /auth/refresh 都不接受此類 token
+		"jti":        jti,
+		"exp":        time.Now().Add(magicLinkTTL).Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	userKey := magicLinkUserPrefix + strconv.FormatUint(uint64(user.ID), 10)
+	// 舊的登入連結作廢
+	if oldJTI, err := config.RDB.Get(ctx, userKey).Result(); err == nil {
+		config.RDB.Del(ctx, magicLinkPrefix+oldJTI)
+	}
+	pipe := config.RDB.TxPipeline()
+	pipe.Set(ctx, magicLinkPrefix+jti, user.ID, magicLinkTTL)
+	pipe.Set(ctx, userKey, jti, magicLinkTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "登入連結",
+		Body: fmt.Sprintf("%s 您好：\n\n請在 %d 分鐘內點擊以下連結登入，連結只能使用一次：\n%s\n\n若您沒有申請登入，請忽略這封信。",
+			user.Username, int(magicLinkTTL.Minutes()), appLink("/magic-link", token)),
+	})
+}
+
+// ConsumeMagicLink 驗證登入連結並回傳使用者，連結使用後立即失效
+// 能收到信代表擁有此信箱，尚未完成 Email 驗證的帳號會一併標記為已驗證
+func (s *Service) ConsumeMagicLink(ctx context.Context, token string) (*models.User, error) {
+	claims, err := utils.ParseToken(token)
+	if err != nil || claims["token_type"] != magicLinkTokenType {
+		return nil, ErrInvalidMagicLink
+	}
+	jti, _ := claims["jti"].(string)
+	userID, ok := utils.ClaimUserID(claims)
+	if jti == "" || !ok {
+		return nil, ErrInvalidMagicLink
+	}
+
+	stored, err := config.RDB.GetDel(ctx, magicLinkPrefix+jti).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrInvalidMagicLink
+	}
+	if err != nil {
+		return nil, err
+	}
+	if stored != strconv.FormatUint(uint64(userID), 10) {
+		return nil, ErrInvalidMagicLink
+	}
+	config.RDB.Del(ctx, magicLinkUserPrefix+stored)
+
+	var user models.User
+	if err := config.DB.WithContext(ctx).First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidMagicLink
+		}
+		return nil, err
+	}
+	if user.EmailVerifiedAt == nil {
+		now := time.Now()
+		if err := config.DB.WithContext(ctx).Model(&user).Update("email_verified_at", now).Error; err != nil {
+			return nil, err
+		}
+		user.EmailVerifiedAt = &now
+	}
+	return &user, nil
+}
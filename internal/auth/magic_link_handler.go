@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"log"
+	"micro-golang/internal/dto"
+	"micro-golang/internal/utils"
+)
+
+/**
+ * @File: magic_link_handler.go
+ * @Description:
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/21 下午2:30
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// RequestMagicLink 申請免密碼登入連結（有限流）
+// 不論 Email 是否存在都回傳相同訊息，避免被拿來探測帳號
+func (h *Handler) RequestMagicLink(c *gin.Context) {
+	var input dto.MagicLinkDTO
+	if err := c.ShouldBindJSON(&input); err != nil {
+		var ve validator.ValidationErrors
+		if errors.As(err, &ve) {
+			utils.ReturnError(c, utils.CodeParamInvalid, utils.ExtractFieldErrorMessages(input, ve), "欄位驗證失敗")
+			return
+		}
+		utils.ReturnError(c, utils.CodeParamInvalid, err.Error())
+		return
+	}
+
+	if err := h.authService.SendMagicLink(c, input.Email, c.ClientIP()); err != nil {
+		if errors.Is(err, ErrTooManyRequests) {
+			utils.ReturnError(c, utils.CodeTooManyRequests, nil, "申請次數過多，請稍後再試")
+			return
+		}
+		log.Printf("❌ 寄送登入連結失敗 (%s)：%v", input.Email, err)
+	}
+	utils.ReturnSuccess(c, nil, "若該 Email 已註冊，您將會收到登入連結")
+}
+
+// ConsumeMagicLink 以登入連結中的 token 登入（GET /auth/magic-link/consume?token=），回應與 Login 相同
+func (h *Handler) ConsumeMagicLink(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		utils.ReturnError(c, utils.CodeParamInvalid, nil, "請提供 token")
+		return
+	}
+
+	user, err := h.authService.ConsumeMagicLink(c, token)
+	if err != nil {
+		if errors.Is(err, ErrInvalidMagicLink) {
+			utils.ReturnError(c, utils.CodeUnauthorized, nil, "登入連結無效、已過期或已使用，請重新申請")
+			return
+		}
+		utils.ReturnError(c, utils.CodeServerError, nil, "登入失敗")
+		return
+	}
+
+	if !user.IsActive {
+		utils.ReturnError(c, utils.CodeAccountInactive, nil, "帳號已停用")
+		return
+	}
+	// 登入連結取代的是密碼，已啟用兩步驟驗證時仍需驗證第二因素
+	if user.TOTPEnabled {
+		h.startMFAChallenge(c, *user)
+		return
+	}
+	h.completeLogin(c, *user, "Login successful")
+}
@@ -0,0 +1,293 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"micro-golang/internal/audit"
+	"micro-golang/internal/config"
+	"micro-golang/internal/dto"
+	"micro-golang/internal/models"
+	"micro-golang/internal/password"
+	"micro-golang/internal/rbac"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/**
+ * @File: mfa.go
+ * @Description:
+ *
+ * TOTP 兩步驟驗證：設定 / 確認 / 停用 / 登入時的 challenge 驗證
+ * 密碼正確但已啟用 MFA 時，Login 只發出短效的 challenge token，
+ * 必須搭配 TOTP 驗證碼或復原碼才能換取正式的 access / refresh token。
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/19 上午10:20
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+const (
+	mfaChallengePrefix      = "mfa_challenge:"          // mfa_challenge:<token hash> → userId
+	mfaChallengeAttempts    = "mfa_challenge:attempts:" // 每個 challenge 的驗證失敗次數
+	mfaChallengeTTL         = 5 * time.Minute
+	mfaChallengeMaxAttempts = 5
+	totpUsedPrefix          = "mfa:totp_used:" // 已使用過的時間區間，防止驗證碼被重放
+	recoveryCodeCount       = 10
+	mfaIssuer               = "MicroGo"
+)
+
+var (
+	ErrMFAAlreadyEnabled = errors.New("mfa already enabled")
+	ErrMFANotSetup       = errors.New("mfa setup not started")
+	ErrMFANotEnabled     = errors.New("mfa not enabled")
+	ErrInvalidMFACode    = errors.New("invalid mfa code")
+	ErrInvalidChallenge  = errors.New("invalid or expired mfa challenge")
+)
+
+// requiresMFA 管理者角色依資安規範必須啟用兩步驟驗證
+func requiresMFA(role string) bool {
+	return role == rbac.RoleAdmin || role == rbac.RoleSuperAdmin
+}
+
+// SetupTOTP 產生新的 TOTP 金鑰與復原碼，需再呼叫 ConfirmTOTP 驗證一次才會啟用
+func (s *Service) SetupTOTP(ctx context.Context, userID uint) (*dto.TOTPSetupDTO, error) {
+	var user models.User
+	if err := config.DB.WithContext(ctx).First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret := generateTOTPSecret()
+	encrypted, err := encryptSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	rows := make([]models.MFARecoveryCode, recoveryCodeCount)
+	for i := range codes {
+		codes[i] = newRecoveryCode()
+		rows[i] = models.MFARecoveryCode{UserID: user.ID, CodeHash: hashToken(normalizeRecoveryCode(codes[i]))}
+	}
+
+	// 重新設定時舊的復原碼一併作廢
+	err = config.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Update("totp_secret", encrypted).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.MFARecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&rows).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.TOTPSetupDTO{
+		OtpauthURI:    totpURI(mfaIssuer, user.Email, secret),
+		Secret:        secret,
+		RecoveryCodes: codes,
+	}, nil
+}
+
+// ConfirmTOTP 以 App 產生的驗證碼確認設定，成功後啟用兩步驟驗證
+func (s *Service) ConfirmTOTP(ctx context.Context, userID uint, code string) error {
+	var user models.User
+	if err := config.DB.WithContext(ctx).First(&user, userID).Error; err != nil {
+		return err
+	}
+	if user.TOTPEnabled {
+		return ErrMFAAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return ErrMFANotSetup
+	}
+
+	ok, err := s.verifyTOTP(ctx, user, code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidMFACode
+	}
+	return config.DB.WithContext(ctx).Model(&user).Update("totp_enabled", true).Error
+}
+
+// DisableTOTP 使用者自行停用兩步驟驗證，需再次輸入密碼與 TOTP 驗證碼（或復原碼）
+// 錯誤計入登入失敗次數；管理者角色停用後，下次登入或 refresh 取得的 token 會限定只能重新設定 TOTP
+func (s *Service) DisableTOTP(ctx context.Context, actor audit.Actor, plain string, code string) error {
+	var user models.User
+	if err := config.DB.WithContext(ctx).First(&user, actor.ID).Error; err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return ErrMFANotEnabled
+	}
+	if wait := lockRemaining(ctx, "email:"+strings.ToLower(user.Email), "ip:"+actor.IP); wait > 0 {
+		return &LoginLockedError{RetryAfter: wait}
+	}
+
+	// 外部登入建立、沒有密碼的帳號無法自行停用，需由管理者重設
+	passwordOK := false
+	if user.Password != "" {
+		passwordOK, _ = password.Verify(plain, user.Password)
+	}
+	codeOK := false
+	if passwordOK {
+		var err error
+		if codeOK, err = s.verifySecondFactor(ctx, user, code); err != nil {
+			return err
+		}
+	}
+	if !passwordOK || !codeOK {
+		if wait := recordLoginFailure(ctx, user.Email, actor.IP); wait > 0 {
+			return &LoginLockedError{RetryAfter: wait}
+		}
+		if !passwordOK {
+			return ErrInvalidCredentials
+		}
+		return ErrInvalidMFACode
+	}
+
+	return config.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(map[string]interface{}{"totp_enabled": false, "totp_secret": ""}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.MFARecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, audit.Entry{
+			Actor:      actor,
+			Action:     audit.ActionMFADisable,
+			TargetType: "user",
+			TargetID:   strconv.FormatUint(uint64(user.ID), 10),
+		})
+	})
+}
+
+// CreateMFAChallenge 密碼驗證通過後發出 challenge token
+func (s *Service) CreateMFAChallenge(ctx context.Context, user models.User) (string, error) {
+	token, hash := newOpaqueToken()
+	if err := config.RDB.Set(ctx, mfaChallengePrefix+hash, user.ID, mfaChallengeTTL).Err(); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// VerifyMFAChallenge 以 TOTP 驗證碼或復原碼完成 challenge，成功時回傳使用者
+// 每個 challenge 最多嘗試 mfaChallengeMaxAttempts 次，超過就作廢必須重新輸入密碼；
+// 驗證碼錯誤也計入 Email / IP 的登入失敗次數，鎖定期間不接受驗證
+func (s *Service) VerifyMFAChallenge(ctx context.Context, challengeToken string, code string, clientIP string) (*models.User, error) {
+	hash := hashToken(challengeToken)
+	userIDStr, err := config.RDB.Get(ctx, mfaChallengePrefix+hash).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrInvalidChallenge
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if ok, err := allow(ctx, mfaChallengeAttempts+hash, mfaChallengeMaxAttempts, mfaChallengeTTL); err != nil {
+		return nil, err
+	} else if !ok {
+		config.RDB.Del(ctx, mfaChallengePrefix+hash)
+		return nil, ErrInvalidChallenge
+	}
+
+	userID, err := strconv.ParseUint(userIDStr, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidChallenge
+	}
+	var user models.User
+	if err := config.DB.WithContext(ctx).First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+
+	if wait := lockRemaining(ctx, "email:"+strings.ToLower(user.Email), "ip:"+clientIP); wait > 0 {
+		return nil, &LoginLockedError{RetryAfter: wait}
+	}
+
+	ok, err := s.verifySecondFactor(ctx, user, code)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if wait := recordLoginFailure(ctx, user.Email, clientIP); wait > 0 {
+			// 鎖定後這個 challenge 也作廢，解鎖後需重新輸入密碼
+			config.RDB.Del(ctx, mfaChallengePrefix+hash)
+			return nil, &LoginLockedError{RetryAfter: wait}
+		}
+		return nil, ErrInvalidMFACode
+	}
+
+	// 同一個 challenge 只能換一次 token，併發請求只有一個會成功刪除
+	if deleted, err := config.RDB.Del(ctx, mfaChallengePrefix+hash).Result(); err != nil || deleted == 0 {
+		return nil, ErrInvalidChallenge
+	}
+	config.RDB.Del(ctx, mfaChallengeAttempts+hash)
+	clearLoginFailures(ctx, user.Email)
+	return &user, nil
+}
+
+// verifySecondFactor 6 位數字視為 TOTP 驗證碼，其他格式視為復原碼
+func (s *Service) verifySecondFactor(ctx context.Context, user models.User, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+	if len(code) == totpDigits {
+		return s.verifyTOTP(ctx, user, code)
+	}
+	return s.useRecoveryCode(ctx, user.ID, code)
+}
+
+// verifyTOTP 驗證 TOTP，同一個時間區間的驗證碼只接受一次
+func (s *Service) verifyTOTP(ctx context.Context, user models.User, code string) (bool, error) {
+	secret, err := decryptSecret(user.TOTPSecret)
+	if err != nil {
+		return false, err
+	}
+	step, ok := validateTOTP(secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+
+	key := fmt.Sprintf("%s%d:%d", totpUsedPrefix, user.ID, step)
+	fresh, err := config.RDB.SetNX(ctx, key, "1", (2*totpSkew+1)*totpPeriod*time.Second).Result()
+	if err != nil {
+		return false, err
+	}
+	return fresh, nil
+}
+
+// useRecoveryCode 使用一組復原碼，以條件更新確保併發時只能用一次
+func (s *Service) useRecoveryCode(ctx context.Context, userID uint, code string) (bool, error) {
+	result := config.DB.WithContext(ctx).Model(&models.MFARecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hashToken(normalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// newRecoveryCode 產生 xxxxx-xxxxx 格式的復原碼（50 bits）
+func newRecoveryCode() string {
+	b := make([]byte, 7)
+	_, _ = rand.Read(b)
+	code := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))[:10]
+	return code[:5] + "-" + code[5:]
+}
+
+// normalizeRecoveryCode 忽略大小寫、空白與連字號，方便使用者輸入
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.ReplaceAll(code, "-", "")
+}
@@ -0,0 +1,163 @@
+package auth
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"micro-golang/internal/audit"
+	"micro-golang/internal/dto"
+	"micro-golang/internal/models"
+	"micro-golang/internal/utils"
+)
+
+/**
+ * @File: mfa_handler.go
+ * @Description:
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/19 上午11:00
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// SetupTOTP 開始設定 TOTP，回傳 otpauth URI 與復原碼（需登入）
+func (h *Handler) SetupTOTP(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		utils.ReturnError(c, utils.CodeUnauthorized, nil, "Token 內容無效")
+		return
+	}
+
+	setup, err := h.authService.SetupTOTP(c, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrMFAAlreadyEnabled):
+			utils.ReturnError(c, utils.CodeBadRequest, nil, "已啟用兩步驟驗證")
+		case errors.Is(err, ErrEncryptionKeyMissing):
+			utils.ReturnError(c, utils.CodeServerError, nil, "伺服器尚未設定 MFA 加密金鑰")
+		default:
+			utils.ReturnError(c, utils.CodeServerError, nil, "設定兩步驟驗證失敗")
+		}
+		return
+	}
+	utils.ReturnSuccess(c, setup, "請以驗證 App 掃描後輸入驗證碼完成設定，並妥善保存復原碼")
+}
+
+// ConfirmTOTP 輸入 App 上的驗證碼，確認後正式啟用兩步驟驗證（需登入）
+func (h *Handler) ConfirmTOTP(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		utils.ReturnError(c, utils.CodeUnauthorized, nil, "Token 內容無效")
+		return
+	}
+
+	var input dto.TOTPConfirmDTO
+	if err := c.ShouldBindJSON(&input); err != nil {
+		var ve validator.ValidationErrors
+		if errors.As(err, &ve) {
+			utils.ReturnError(c, utils.CodeParamInvalid, utils.ExtractFieldErrorMessages(input, ve), "欄位驗證失敗")
+			return
+		}
+		utils.ReturnError(c, utils.CodeParamInvalid, err.Error())
+		return
+	}
+
+	if err := h.authService.ConfirmTOTP(c, userID, input.Code); err != nil {
+		switch {
+		case errors.Is(err, ErrMFAAlreadyEnabled):
+			utils.ReturnError(c, utils.CodeBadRequest, nil, "已啟用兩步驟驗證")
+		case errors.Is(err, ErrMFANotSetup):
+			utils.ReturnError(c, utils.CodeBadRequest, nil, "請先呼叫 /auth/mfa/totp/setup")
+		case errors.Is(err, ErrInvalidMFACode):
+			utils.ReturnError(c, utils.CodeUnauthorized, nil, "驗證碼錯誤")
+		default:
+			utils.ReturnError(c, utils.CodeServerError, nil, "確認兩步驟驗證失敗")
+		}
+		return
+	}
+	utils.ReturnSuccess(c, nil, "兩步驟驗證已啟用")
+}
+
+// DisableTOTP 停用兩步驟驗證（需登入，並再次輸入密碼與驗證碼）
+func (h *Handler) DisableTOTP(c *gin.Context) {
+	var input dto.TOTPDisableDTO
+	if err := c.ShouldBindJSON(&input); err != nil {
+		var ve validator.ValidationErrors
+		if errors.As(err, &ve) {
+			utils.ReturnError(c, utils.CodeParamInvalid, utils.ExtractFieldErrorMessages(input, ve), "欄位驗證失敗")
+			return
+		}
+		utils.ReturnError(c, utils.CodeParamInvalid, err.Error())
+		return
+	}
+
+	actor := audit.ActorFromContext(c)
+	if actor.ID == 0 {
+		utils.ReturnError(c, utils.CodeUnauthorized, nil, "Token 內容無效")
+		return
+	}
+
+	if err := h.authService.DisableTOTP(c, actor, input.CurrentPassword, input.Code); err != nil {
+		var locked *LoginLockedError
+		switch {
+		case errors.As(err, &locked):
+			respondLoginLocked(c, locked.RetryAfter)
+		case errors.Is(err, ErrMFANotEnabled):
+			utils.ReturnError(c, utils.CodeBadRequest, nil, "尚未啟用兩步驟驗證")
+		case errors.Is(err, ErrInvalidCredentials):
+			utils.ReturnError(c, utils.CodeInvalidCredentials, nil, "目前密碼錯誤")
+		case errors.Is(err, ErrInvalidMFACode):
+			utils.ReturnError(c, utils.CodeUnauthorized, nil, "驗證碼或復原碼錯誤")
+		default:
+			utils.ReturnError(c, utils.CodeServerError, nil, "停用兩步驟驗證失敗")
+		}
+		return
+	}
+	utils.ReturnSuccess(c, nil, "兩步驟驗證已停用")
+}
+
+// VerifyMFA 登入第二步：以 challenge token 加上 TOTP 驗證碼或復原碼換取正式 token
+func (h *Handler) VerifyMFA(c *gin.Context) {
+	var input dto.MFAVerifyDTO
+	if err := c.ShouldBindJSON(&input); err != nil {
+		var ve validator.ValidationErrors
+		if errors.As(err, &ve) {
+			utils.ReturnError(c, utils.CodeParamInvalid, utils.ExtractFieldErrorMessages(input, ve), "欄位驗證失敗")
+			return
+		}
+		utils.ReturnError(c, utils.CodeParamInvalid, err.Error())
+		return
+	}
+
+	user, err := h.authService.VerifyMFAChallenge(c, input.ChallengeToken, input.Code, c.ClientIP())
+	if err != nil {
+		var locked *LoginLockedError
+		switch {
+		case errors.As(err, &locked):
+			respondLoginLocked(c, locked.RetryAfter)
+		case errors.Is(err, ErrInvalidChallenge):
+			utils.ReturnError(c, utils.CodeUnauthorized, nil, "驗證已逾時或嘗試次數過多，請重新登入")
+		case errors.Is(err, ErrInvalidMFACode):
+			utils.ReturnError(c, utils.CodeUnauthorized, nil, "驗證碼或復原碼錯誤")
+		default:
+			utils.ReturnError(c, utils.CodeServerError, nil, "兩步驟驗證失敗")
+		}
+		return
+	}
+
+	h.completeLogin(c, *user, "Login successful")
+}
+
+// startMFAChallenge 密碼正確後回傳 challenge token，等待第二步驗證
+func (h *Handler) startMFAChallenge(c *gin.Context, user models.User) {
+	token, err := h.authService.CreateMFAChallenge(c, user)
+	if err != nil {
+		utils.ReturnError(c, utils.CodeServerError, nil, "無法建立兩步驟驗證")
+		return
+	}
+	utils.ReturnSuccess(c, dto.MFAChallengeDTO{
+		MFARequired:    true,
+		ChallengeToken: token,
+		ExpiresIn:      int(mfaChallengeTTL.Seconds()),
+	}, "請輸入驗證 App 上的驗證碼")
+}
@@ -0,0 +1,277 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+	"micro-golang/internal/config"
+	"micro-golang/internal/dto"
+	"micro-golang/internal/models"
+	"micro-golang/internal/rbac"
+	"micro-golang/internal/utils"
+	"slices"
+	"time"
+)
+
+/**
+ * @File: oauth.go
+ * @Description:
+ *
+ * OAuth 2.1 授權伺服器：authorization_code + PKCE、refresh_token、client_credentials
+ * 授權碼只存雜湊值在 Redis，有效 1 分鐘且只能兌換一次；所有 client 一律要求 PKCE（S256）。
+ * 發出的 access / refresh token 與 /auth/login 相同格式，另外帶上 cid（client_id）與 scope，
+ * scope 為權限名稱，JWTAuth 會放進 context 由 RequirePermission 一併檢查。
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/20 上午10:00
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+const (
+	oauthCodePrefix = "oauth_code:" // oauth_code:<code hash> → authCodeData JSON
+	oauthCodeTTL    = time.Minute
+	// clientTokenTTL client_credentials 發出的 access token 壽命（沒有 refresh token）
+	clientTokenTTL = 5 * time.Minute
+
+	GrantAuthorizationCode = "authorization_code"
+	GrantRefreshToken      = "refresh_token"
+	GrantClientCredentials = "client_credentials"
+)
+
+var (
+	ErrUnknownClient       = errors.New("unknown oauth client")
+	ErrInvalidRedirectURI  = errors.New("redirect_uri not registered")
+	ErrOAuthClientNotFound = errors.New("oauth client not found")
+)
+
+// OAuthError 可直接回給 client 的 OAuth 錯誤（RFC 6749 4.1.2.1 / 5.2）
+type OAuthError struct {
+	Code        string
+	Description string
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+func oauthError(code string, description string) *OAuthError {
+	return &OAuthError{Code: code, Description: description}
+}
+
+// AuthorizeRequest /oauth/authorize 的參數（GET 取自 query，POST 取自表單隱藏欄位）
+type AuthorizeRequest struct {
+	ResponseType        string `form:"response_type"`
+	ClientID            string `form:"client_id"`
+	RedirectURI         string `form:"redirect_uri"`
+	Scope               string `form:"scope"`
+	State               string `form:"state"`
+	CodeChallenge       string `form:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method"`
+	Nonce               string `form:"nonce"` // OIDC，原樣放進 ID token 讓 client 防止重放
+}
+
+// authCodeData 授權碼對應的授權內容
+type authCodeData struct {
+	ClientID      string `json:"client_id"`
+	RedirectURI   string `json:"redirect_uri"`
+	UserID        uint   `json:"user_id"`
+	Scope         string `json:"scope"`
+	CodeChallenge string `json:"code_challenge"`
+	Nonce         string `json:"nonce,omitempty"`
+	AuthTime      int64  `json:"auth_time"` // 使用者在授權頁登入的時間
+}
+
+// CheckAuthorizeRequest 驗證授權請求，成功時回傳 client 與實際授權的 scope
+// client_id / redirect_uri 錯誤時回傳一般 error（不可導回 redirect_uri，只能顯示錯誤頁）；
+// 其餘錯誤回傳 *OAuthError，應導回 redirect_uri 並帶上 error 參數
+func (s *Service) CheckAuthorizeRequest(ctx context.Context, req AuthorizeRequest) (*models.OAuthClient, string, error) {
+	client, err := s.findClient(ctx, req.ClientID)
+	if err != nil {
+		return nil, "", err
+	}
+	if req.RedirectURI == "" || !client.AllowsRedirect(req.RedirectURI) {
+		return nil, "", ErrInvalidRedirectURI
+	}
+
+	if req.ResponseType != "code" {
+		return client, "", oauthError("unsupported_response_type", "僅支援 response_type=code")
+	}
+	if !client.AllowsGrant(GrantAuthorizationCode) {
+		return client, "", oauthError("unauthorized_client", "此 client 不允許 authorization_code")
+	}
+	if req.CodeChallenge == "" || req.CodeChallengeMethod != "S256" {
+		return client, "", oauthError("invalid_request", "需提供 PKCE code_challenge，且 code_challenge_method 必須為 S256")
+	}
+	if len(req.Nonce) > maxNonceLength {
+		return client, "", oauthError("invalid_request", "nonce 過長")
+	}
+
+	scope, err := resolveScope(*client, req.Scope)
+	if err != nil {
+		return client, "", err
+	}
+	return client, scope, nil
+}
+
+// IssueAuthorizationCode 使用者同意授權後發出授權碼
+// 實際授權的 scope 為 client 請求的權限 scope 與使用者角色權限的交集，OIDC scope 則直接保留
+func (s *Service) IssueAuthorizationCode(ctx context.Context, req AuthorizeRequest, scope string, user models.User) (string, error) {
+	var granted []rbac.Permission
+	for _, p := range rbac.ParseScope(scope) {
+		if isOIDCScope(string(p)) || rbac.HasPermission(user.Role, p) {
+			granted = append(granted, p)
+		}
+	}
+	if len(granted) == 0 {
+		return "", oauthError("invalid_scope", "使用者沒有此 client 請求的任何權限")
+	}
+
+	data, _ := json.Marshal(authCodeData{
+		ClientID:      req.ClientID,
+		RedirectURI:   req.RedirectURI,
+		UserID:        user.ID,
+		Scope:         rbac.FormatScope(granted),
+		CodeChallenge: req.CodeChallenge,
+		Nonce:         req.Nonce,
+		AuthTime:      time.Now().Unix(),
+	})
+	code, hash := newOpaqueToken()
+	if err := config.RDB.Set(ctx, oauthCodePrefix+hash, data, oauthCodeTTL).Err(); err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// ExchangeAuthorizationCode 兌換授權碼（只能兌換一次），並驗證 redirect_uri 與 PKCE code_verifier
+func (s *Service) ExchangeAuthorizationCode(ctx context.Context, client models.OAuthClient, code string, redirectURI string, verifier string) (*authCodeData, error) {
+	raw, err := config.RDB.GetDel(ctx, oauthCodePrefix+hashToken(code)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, oauthError("invalid_grant", "授權碼無效、已過期或已使用")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var data authCodeData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, oauthError("invalid_grant", "授權碼無效")
+	}
+	if data.ClientID != client.ClientID || data.RedirectURI != redirectURI {
+		return nil, oauthError("invalid_grant", "授權碼與 client_id 或 redirect_uri 不符")
+	}
+	if !verifyPKCE(verifier, data.CodeChallenge) {
+		return nil, oauthError("invalid_grant", "code_verifier 驗證失敗")
+	}
+	return &data, nil
+}
+
+// AuthenticateClient 驗證 /oauth/token 的 client；public client 不可帶密鑰，confidential client 必須帶正確密鑰
+func (s *Service) AuthenticateClient(ctx context.Context, clientID string, secret string) (*models.OAuthClient, error) {
+	client, err := s.findClient(ctx, clientID)
+	if err != nil {
+		return nil, oauthError("invalid_client", "client 驗證失敗")
+	}
+	if client.Public {
+		if secret != "" {
+			return nil, oauthError("invalid_client", "public client 不可使用 client_secret")
+		}
+		return client, nil
+	}
+	if subtle.ConstantTimeCompare([]byte(hashToken(secret)), []byte(client.SecretHash)) != 1 {
+		return nil, oauthError("invalid_client", "client 驗證失敗")
+	}
+	return client, nil
+}
+
+// ClientCredentialsToken 發出代表 client 本身（服務帳號）的 access token
+func (s *Service) ClientCredentialsToken(client models.OAuthClient, requestedScope string) (*dto.OAuthTokenResponse, error) {
+	if client.Public || !client.AllowsGrant(GrantClientCredentials) {
+		return nil, oauthError("unauthorized_client", "此 client 不允許 client_credentials")
+	}
+	scope, err := resolveScope(client, requestedScope)
+	if err != nil {
+		return nil, err
+	}
+	// 服務帳號沒有使用者身分，OIDC scope 沒有意義
+	var perms []rbac.Permission
+	for _, p := range rbac.ParseScope(scope) {
+		if !isOIDCScope(string(p)) {
+			perms = append(perms, p)
+		}
+	}
+	scope = rbac.FormatScope(perms)
+
+	token, err := utils.SignClaims(jwt.MapClaims{
+		"sub":   client.ClientID,
+		"cid":   client.ClientID,
+		"role":  client.ServiceRole,
+		"scope": scope,
+		"jti":   utils.NewTokenID(),
+		"exp":   time.Now().Add(clientTokenTTL).Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &dto.OAuthTokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(clientTokenTTL.Seconds()),
+		Scope:       scope,
+	}, nil
+}
+
+// activeUser 取得授權碼對應的使用者，帳號已停用時授權失效
+func (s *Service) activeUser(ctx context.Context, userID uint) (*models.User, error) {
+	var user models.User
+	if err := config.DB.WithContext(ctx).First(&user, userID).Error; err != nil || !user.IsActive {
+		return nil, oauthError("invalid_grant", "使用者不存在或已停用")
+	}
+	return &user, nil
+}
+
+func (s *Service) findClient(ctx context.Context, clientID string) (*models.OAuthClient, error) {
+	if clientID == "" {
+		return nil, ErrUnknownClient
+	}
+	var client models.OAuthClient
+	if err := config.DB.WithContext(ctx).Where("client_id = ?", clientID).First(&client).Error; err != nil {
+		return nil, ErrUnknownClient
+	}
+	return &client, nil
+}
+
+// resolveScope 檢查請求的 scope 是否都在 client 允許範圍內，未指定時使用 client 全部的 scope
+func resolveScope(client models.OAuthClient, requested string) (string, error) {
+	allowed := rbac.ParseScope(client.Scopes)
+	perms := rbac.ParseScope(requested)
+	if len(perms) == 0 {
+		return rbac.FormatScope(allowed), nil
+	}
+	for _, p := range perms {
+		if !slices.Contains(allowed, p) {
+			return "", oauthError("invalid_scope", fmt.Sprintf("此 client 不允許 scope %s", p))
+		}
+	}
+	return rbac.FormatScope(perms), nil
+}
+
+// verifyPKCE 驗證 code_verifier（RFC 7636，只接受 S256）
+func verifyPKCE(verifier string, challenge string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(pkceChallenge(verifier)), []byte(challenge)) == 1
+}
+
+// pkceChallenge 計算 S256 code_challenge
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
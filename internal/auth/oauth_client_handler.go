@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"micro-golang/internal/audit"
+	"micro-golang/internal/dto"
+	"micro-golang/internal/utils"
+)
+
+/**
+ * @File: oauth_client_handler.go
+ * @Description:
+ *
+ * OAuth client 管理 API（/admin/oauth/clients），需具備 oauth_client:manage 權限
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/20 上午11:50
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// CreateOAuthClient 註冊 OAuth client
+func (h *Handler) CreateOAuthClient(c *gin.Context) {
+	var req dto.OAuthClientCreateDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		var ve validator.ValidationErrors
+		if errors.As(err, &ve) {
+			utils.ReturnError(c, utils.CodeParamInvalid, utils.ExtractFieldErrorMessages(req, ve), "欄位驗證失敗")
+			return
+		}
+		utils.ReturnError(c, utils.CodeParamInvalid, err.Error())
+		return
+	}
+
+	created, err := h.authService.CreateOAuthClient(c, audit.ActorFromContext(c), req)
+	if err != nil {
+		var ce *ClientConfigError
+		if errors.As(err, &ce) {
+			utils.ReturnError(c, utils.CodeParamInvalid, nil, ce.Reason)
+			return
+		}
+		utils.ReturnError(c, utils.CodeServerError, nil, "註冊 OAuth client 失敗")
+		return
+	}
+	utils.ReturnSuccess(c, created, "請妥善保存 client_secret，之後將無法再次查看")
+}
+
+// ListOAuthClients 列出所有 OAuth client
+func (h *Handler) ListOAuthClients(c *gin.Context) {
+	clients, err := h.authService.ListOAuthClients(c)
+	if err != nil {
+		utils.ReturnError(c, utils.CodeServerError, nil, "無法取得 OAuth client")
+		return
+	}
+	utils.ReturnSuccess(c, clients)
+}
+
+// DeleteOAuthClient 刪除 OAuth client
+func (h *Handler) DeleteOAuthClient(c *gin.Context) {
+	err := h.authService.DeleteOAuthClient(c, audit.ActorFromContext(c), c.Param("clientId"))
+	if err != nil {
+		if errors.Is(err, ErrOAuthClientNotFound) {
+			utils.ReturnError(c, utils.CodeNotFound, nil, "找不到此 OAuth client")
+			return
+		}
+		utils.ReturnError(c, utils.CodeServerError, nil, "刪除 OAuth client 失敗")
+		return
+	}
+	utils.ReturnSuccess(c, nil, "OAuth client deleted")
+}
@@ -0,0 +1,337 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"log"
+	"micro-golang/internal/dto"
+	"micro-golang/internal/models"
+	"micro-golang/internal/utils"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+/**
+ * @File: oauth_handler.go
+ * @Description:
+ *
+ * OAuth 2.1 端點：/oauth/authorize（登入 + 同意頁）與 /oauth/token
+ * 這兩個端點的錯誤格式依 RFC 6749，不使用 utils.JsonResult。
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/20 上午11:20
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+const oauthCSRFCookie = "oauth_csrf"
+
+// Authorize 顯示登入 / 同意授權頁面
+func (h *Handler) Authorize(c *gin.Context) {
+	var req AuthorizeRequest
+	_ = c.ShouldBindQuery(&req)
+
+	client, scope, err := h.authService.CheckAuthorizeRequest(c, req)
+	if err != nil {
+		h.authorizeFailed(c, req, err)
+		return
+	}
+
+	csrf, _ := newOpaqueToken()
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oauthCSRFCookie, csrf, 600, "/oauth", "", isHTTPS(c), true)
+	req.Scope = scope
+	renderAuthorizePage(c, http.StatusOK, authorizePageData{
+		ClientName: client.Name,
+		Scopes:     strings.Fields(scope),
+		Request:    req,
+		CSRF:       csrf,
+	})
+}
+
+// AuthorizeSubmit 處理授權頁面送出的登入 / 同意結果，成功時帶授權碼導回 redirect_uri
+func (h *Handler) AuthorizeSubmit(c *gin.Context) {
+	var req AuthorizeRequest
+	_ = c.ShouldBind(&req)
+
+	client, scope, err := h.authService.CheckAuthorizeRequest(c, req)
+	if err != nil {
+		h.authorizeFailed(c, req, err)
+		return
+	}
+
+	// 表單的 CSRF token 必須與 cookie 相同，避免被其他網站代為送出登入
+	cookie, _ := c.Cookie(oauthCSRFCookie)
+	csrf := c.PostForm("csrf_token")
+	if cookie == "" || subtle.ConstantTimeCompare([]byte(cookie), []byte(csrf)) != 1 {
+		renderAuthorizeError(c, http.StatusBadRequest, "頁面已過期，請回到應用程式重新登入")
+		return
+	}
+
+	if c.PostForm("decision") != "approve" {
+		redirectWithParams(c, req.RedirectURI, url.Values{
+			"error":             {"access_denied"},
+			"error_description": {"使用者拒絕授權"},
+			"state":             {req.State},
+		})
+		return
+	}
+
+	email := c.PostForm("email")
+	page := authorizePageData{
+		ClientName: client.Name,
+		Scopes:     strings.Fields(scope),
+		Request:    req,
+		CSRF:       csrf,
+		Email:      email,
+	}
+	retry := func(msg string) {
+		page.Error = msg
+		renderAuthorizePage(c, http.StatusOK, page)
+	}
+
+	user, err := h.authService.Authenticate(c, email, c.PostForm("password"), c.ClientIP())
+	if err != nil {
+		var locked *LoginLockedError
+		switch {
+		case errors.As(err, &locked):
+			retry(fmt.Sprintf("登入失敗次數過多，請於 %d 秒後再試", int(locked.RetryAfter.Seconds())+1))
+		case errors.Is(err, ErrInvalidCredentials):
+			retry("Email 或密碼錯誤")
+		default:
+			retry("登入失敗，請稍後再試")
+		}
+		return
+	}
+	if !user.IsActive {
+		retry("帳號已停用")
+		return
+	}
+	if user.EmailVerifiedAt == nil {
+		retry("Email 尚未驗證，請至信箱點擊驗證連結")
+		return
+	}
+	if user.TOTPEnabled {
+		otp := strings.TrimSpace(c.PostForm("otp"))
+		if otp == "" {
+			retry("此帳號已啟用兩步驟驗證，請輸入驗證碼")
+			return
+		}
+		ok, err := h.authService.verifySecondFactor(c, *user, otp)
+		if err != nil {
+			retry("驗證失敗，請稍後再試")
+			return
+		}
+		if !ok {
+			recordLoginFailure(c, user.Email, c.ClientIP())
+			retry("驗證碼錯誤")
+			return
+		}
+		clearLoginFailures(c, user.Email)
+	}
+
+	code, err := h.authService.IssueAuthorizationCode(c, req, scope, *user)
+	if err != nil {
+		h.authorizeFailed(c, req, err)
+		return
+	}
+	c.SetCookie(oauthCSRFCookie, "", -1, "/oauth", "", isHTTPS(c), true)
+	redirectWithParams(c, req.RedirectURI, url.Values{"code": {code}, "state": {req.State}})
+}
+
+// Token /oauth/token，支援 authorization_code、refresh_token、client_credentials
+func (h *Handler) Token(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	client, err := h.clientFromRequest(c)
+	if err != nil {
+		oauthTokenError(c, err)
+		return
+	}
+
+	grantType := c.PostForm("grant_type")
+	if grantType == "" || !client.AllowsGrant(grantType) {
+		oauthTokenError(c, oauthError("unauthorized_client", "此 client 不允許 grant_type "+grantType))
+		return
+	}
+
+	switch grantType {
+	case GrantAuthorizationCode:
+		h.tokenFromAuthorizationCode(c, *client)
+	case GrantRefreshToken:
+		h.tokenFromRefreshToken(c, *client)
+	case GrantClientCredentials:
+		resp, err := h.authService.ClientCredentialsToken(*client, c.PostForm("scope"))
+		if err != nil {
+			oauthTokenError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+	default:
+		oauthTokenError(c, oauthError("unsupported_grant_type", "不支援的 grant_type"))
+	}
+}
+
+// clientFromRequest client 驗證：HTTP Basic 或表單的 client_id / client_secret
+func (h *Handler) clientFromRequest(c *gin.Context) (*models.OAuthClient, error) {
+	clientID, secret, ok := c.Request.BasicAuth()
+	if !ok {
+		clientID, secret = c.PostForm("client_id"), c.PostForm("client_secret")
+	}
+	return h.authService.AuthenticateClient(c, clientID, secret)
+}
+
+// tokenFromAuthorizationCode 以授權碼 + code_verifier 換發 token，並開一個新的 session
+func (h *Handler) tokenFromAuthorizationCode(c *gin.Context, client models.OAuthClient) {
+	data, err := h.authService.ExchangeAuthorizationCode(c, client, c.PostForm("code"), c.PostForm("redirect_uri"), c.PostForm("code_verifier"))
+	if err != nil {
+		oauthTokenError(c, err)
+		return
+	}
+
+	user, err := h.authService.activeUser(c, data.UserID)
+	if err != nil {
+		oauthTokenError(c, err)
+		return
+	}
+
+	grant := jwt.MapClaims{"cid": client.ClientID, "scope": data.Scope}
+	accessToken, refreshToken, err := startSession(c, *user, grant)
+	if err != nil {
+		oauthTokenError(c, err)
+		return
+	}
+
+	resp := dto.OAuthTokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(utils.AccessTokenTTL.Seconds()),
+		Scope:       data.Scope,
+	}
+	if client.AllowsGrant(GrantRefreshToken) {
+		resp.RefreshToken = refreshToken
+	}
+	if slices.Contains(strings.Fields(data.Scope), ScopeOpenID) {
+		resp.IDToken, err = issueIDToken(profileOf(*user), user.EmailVerifiedAt != nil, client.ClientID, data.Scope, data.Nonce, data.AuthTime)
+		if err != nil {
+			oauthTokenError(c, err)
+			return
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// tokenFromRefreshToken 輪替 refresh token，token 必須是發給同一個 client 的
+func (h *Handler) tokenFromRefreshToken(c *gin.Context, client models.OAuthClient) {
+	result, err := refreshTokens(c, c.PostForm("refresh_token"), client.ClientID, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrRefreshTokenInvalid), errors.Is(err, ErrRefreshTokenReused),
+			errors.Is(err, ErrRefreshTokenRevoked), errors.Is(err, ErrAccountInactive), errors.Is(err, ErrSessionRevoked):
+			oauthTokenError(c, oauthError("invalid_grant", "refresh_token 無效、已使用或已撤銷"))
+		default:
+			oauthTokenError(c, err)
+		}
+		return
+	}
+
+	scope, _ := result.Grant["scope"].(string)
+	resp := dto.OAuthTokenResponse{
+		AccessToken:  result.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(utils.AccessTokenTTL.Seconds()),
+		RefreshToken: result.RefreshToken,
+		Scope:        scope,
+	}
+	// OIDC：refresh 時一併換發 ID token（不帶 nonce，auth_time 未知）
+	if slices.Contains(strings.Fields(scope), ScopeOpenID) {
+		resp.IDToken, err = issueIDToken(profileOf(result.User), result.User.EmailVerifiedAt != nil, client.ClientID, scope, "", 0)
+		if err != nil {
+			oauthTokenError(c, err)
+			return
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// authorizeFailed 授權請求錯誤：client / redirect_uri 有問題時只能顯示錯誤頁，其餘導回 redirect_uri
+func (h *Handler) authorizeFailed(c *gin.Context, req AuthorizeRequest, err error) {
+	var oe *OAuthError
+	switch {
+	case errors.Is(err, ErrUnknownClient):
+		renderAuthorizeError(c, http.StatusBadRequest, "未知的應用程式（client_id 錯誤）")
+	case errors.Is(err, ErrInvalidRedirectURI):
+		renderAuthorizeError(c, http.StatusBadRequest, "redirect_uri 與應用程式註冊的不符")
+	case errors.As(err, &oe):
+		redirectWithParams(c, req.RedirectURI, url.Values{
+			"error":             {oe.Code},
+			"error_description": {oe.Description},
+			"state":             {req.State},
+		})
+	default:
+		log.Printf("❌ OAuth 授權失敗：%v", err)
+		redirectWithParams(c, req.RedirectURI, url.Values{"error": {"server_error"}, "state": {req.State}})
+	}
+}
+
+// oauthTokenError /oauth/token 的錯誤回應（RFC 6749 5.2）
+func oauthTokenError(c *gin.Context, err error) {
+	var oe *OAuthError
+	if !errors.As(err, &oe) {
+		log.Printf("❌ OAuth token 發行失敗：%v", err)
+		c.JSON(http.StatusInternalServerError, dto.OAuthErrorResponse{Error: "server_error"})
+		return
+	}
+	status := http.StatusBadRequest
+	if oe.Code == "invalid_client" {
+		status = http.StatusUnauthorized
+		c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+	}
+	c.JSON(status, dto.OAuthErrorResponse{Error: oe.Code, ErrorDescription: oe.Description})
+}
+
+// redirectWithParams 在 redirect_uri 原有的 query 上加上參數後導回（空值的參數略過）
+func redirectWithParams(c *gin.Context, redirectURI string, params url.Values) {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		renderAuthorizeError(c, http.StatusBadRequest, "redirect_uri 格式錯誤")
+		return
+	}
+	q := u.Query()
+	for k, v := range params {
+		if len(v) > 0 && v[0] != "" {
+			q.Set(k, v[0])
+		}
+	}
+	u.RawQuery = q.Encode()
+	c.Redirect(http.StatusFound, u.String())
+}
+
+func renderAuthorizePage(c *gin.Context, status int, data authorizePageData) {
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	// 禁止被嵌入 iframe，避免點擊劫持
+	c.Header("X-Frame-Options", "DENY")
+	c.Status(status)
+	if err := authorizePage.Execute(c.Writer, data); err != nil {
+		log.Printf("❌ 授權頁面輸出失敗：%v", err)
+	}
+}
+
+func renderAuthorizeError(c *gin.Context, status int, msg string) {
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(status)
+	if err := authorizeErrorPage.Execute(c.Writer, msg); err != nil {
+		log.Printf("❌ 授權頁面輸出失敗：%v", err)
+	}
+}
+
+// isHTTPS 經過 nginx 時以 X-Forwarded-Proto 判斷
+func isHTTPS(c *gin.Context) bool {
+	return c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+}
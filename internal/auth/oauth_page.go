@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"html/template"
+)
+
+/**
+ * @File: oauth_page.go
+ * @Description:
+ *
+ * /oauth/authorize 的登入 / 同意授權頁面
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/20 上午11:00
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// authorizePageData 授權頁面需要的資料
+type authorizePageData struct {
+	ClientName string
+	Scopes     []string
+	Request    AuthorizeRequest
+	CSRF       string
+	Email      string
+	Error      string
+}
+
+var authorizePage = template.Must(template.New("authorize").Parse(`<!DOCTYPE html>
+<html lang="zh-Hant">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>登入並授權 {{.ClientName}}</title>
+  <style>
+    body { font-family: sans-serif; max-width: 360px; margin: 48px auto; padding: 0 16px; color: #222; }
+    label { display: block; margin-top: 12px; }
+    input[type=email], input[type=password], input[type=text] { width: 100%; padding: 8px; box-sizing: border-box; }
+    .error { color: #b00020; }
+    .actions { margin-top: 20px; display: flex; gap: 8px; }
+    button { flex: 1; padding: 10px; }
+  </style>
+</head>
+<body>
+  <h2>{{.ClientName}} 想要存取你的帳號</h2>
+  <p>授權後此應用程式可以：</p>
+  <ul>{{range .Scopes}}<li>{{.}}</li>{{end}}</ul>
+  {{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+  <form method="post" action="/oauth/authorize">
+    <input type="hidden" name="csrf_token" value="{{.CSRF}}">
+    <input type="hidden" name="response_type" value="{{.Request.ResponseType}}">
+    <input type="hidden" name="client_id" value="{{.Request.ClientID}}">
+    <input type="hidden" name="redirect_uri" value="{{.Request.RedirectURI}}">
+    <input type="hidden" name="scope" value="{{.Request.Scope}}">
+    <input type="hidden" name="state" value="{{.Request.State}}">
+    <input type="hidden" name="code_challenge" value="{{.Request.CodeChallenge}}">
+    <input type="hidden" name="code_challenge_method" value="{{.Request.CodeChallengeMethod}}">
+    <input type="hidden" name="nonce" value="{{.Request.Nonce}}">
+    <label>Email <input type="email" name="email" value="{{.Email}}" autocomplete="username" required></label>
+    <label>密碼 <input type="password" name="password" autocomplete="current-password" required></label>
+    <label>兩步驟驗證碼（已啟用才需填寫） <input type="text" name="otp" autocomplete="one-time-code"></label>
+    <div class="actions">
+      <button type="submit" name="decision" value="deny" formnovalidate>拒絕</button>
+      <button type="submit" name="decision" value="approve">登入並授權</button>
+    </div>
+  </form>
+</body>
+</html>
+`))
+
+var authorizeErrorPage = template.Must(template.New("authorize_error").Parse(`<!DOCTYPE html>
+<html lang="zh-Hant">
+<head><meta charset="utf-8"><title>授權失敗</title></head>
+<body style="font-family: sans-serif; max-width: 360px; margin: 48px auto;">
+  <h2>無法完成授權</h2>
+  <p>{{.}}</p>
+</body>
+</html>
+`))
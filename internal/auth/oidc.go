@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"micro-golang/internal/config"
+	"micro-golang/internal/dto"
+	"micro-golang/internal/models"
+	"micro-golang/internal/rbac"
+	"micro-golang/internal/utils"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/**
+ * @File: oidc.go
+ * @Description:
+ *
+ * OpenID Connect：ID token、標準 claims 與 discovery 文件
+ * OIDC 的 openid / profile / email scope 不是權限，授權時會與權限 scope 一起保留。
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/20 下午2:20
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+const (
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+
+	idTokenTTL     = 10 * time.Minute
+	maxNonceLength = 255
+)
+
+// oidcScopes OIDC 定義的 scope
+var oidcScopes = []string{ScopeOpenID, ScopeProfile, ScopeEmail}
+
+func isOIDCScope(scope string) bool {
+	return slices.Contains(oidcScopes, scope)
+}
+
+// issuerURL OIDC issuer，需為其他應用程式實際連到的網址（經過 nginx 時為 gateway 網址）
+func issuerURL() string {
+	return strings.TrimRight(config.GetEnv("OIDC_ISSUER", config.GetEnv("AUTH_BASE_URL", "http://localhost:7001")), "/")
+}
+
+// profileOf 取出 claims 需要的使用者資料
+func profileOf(user models.User) dto.UserLoginResponseDTO {
+	return dto.UserLoginResponseDTO{
+		ID:       user.ID,
+		Email:    user.Email,
+		Username: user.Username,
+		Role:     user.Role,
+	}
+}
+
+// userClaims 依 scope 組出使用者的標準 claims（ID token 與 /userinfo 共用）
+func userClaims(profile dto.UserLoginResponseDTO, emailVerified bool, scopes []string) jwt.MapClaims {
+	claims := jwt.MapClaims{"sub": strconv.FormatUint(uint64(profile.ID), 10)}
+	if slices.Contains(scopes, ScopeProfile) {
+		claims["name"] = profile.Username
+		claims["preferred_username"] = profile.Username
+	}
+	if slices.Contains(scopes, ScopeEmail) {
+		claims["email"] = profile.Email
+		claims["email_verified"] = emailVerified
+	}
+	return claims
+}
+
+// issueIDToken 簽發 ID token；authTime 為使用者實際登入的時間，refresh 換發時未知則傳 0
+func issueIDToken(profile dto.UserLoginResponseDTO, emailVerified bool, clientID string, scope string, nonce string, authTime int64) (string, error) {
+	now := time.Now()
+	claims := userClaims(profile, emailVerified, strings.Fields(scope))
+	claims["iss"] = issuerURL()
+	claims["aud"] = clientID
+	claims["azp"] = clientID
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(idTokenTTL).Unix()
+	// 自訂的 token_type 讓 JWTAuth 拒絕把 ID token 當成 access token 使用
+	claims["token_type"] = "id"
+	if nonce != "" {
+		claims["nonce"] = nonce
+	}
+	if authTime > 0 {
+		claims["auth_time"] = authTime
+	}
+	return utils.SignClaims(claims)
+}
+
+// discoveryDocument /.well-known/openid-configuration
+func discoveryDocument() gin.H {
+	issuer := issuerURL()
+	scopes := slices.Clone(oidcScopes)
+	for _, p := range rbac.Permissions(rbac.RoleSuperAdmin) {
+		scopes = append(scopes, string(p))
+	}
+	slices.Sort(scopes[len(oidcScopes):])
+	return gin.H{
+		"issuer":                                issuer,
+		"authorization_endpoint":                issuer + "/oauth/authorize",
+		"token_endpoint":                        issuer + "/oauth/token",
+		"userinfo_endpoint":                     issuer + "/userinfo",
+		"jwks_uri":                              issuer + "/.well-known/jwks.json",
+		"introspection_endpoint":                issuer + "/auth/introspect",
+		"revocation_endpoint":                   issuer + "/auth/revoke",
+		"response_types_supported":              []string{"code"},
+		"grant_types_supported":                 []string{GrantAuthorizationCode, GrantRefreshToken, GrantClientCredentials},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256", "EdDSA"},
+		"scopes_supported":                      scopes,
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post", "none"},
+		"code_challenge_methods_supported":      []string{"S256"},
+		"claims_supported":                      []string{"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "name", "preferred_username", "email", "email_verified"},
+	}
+}
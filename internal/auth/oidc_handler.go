@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"log"
+	"micro-golang/internal/config"
+	"micro-golang/internal/models"
+	"micro-golang/internal/rbac"
+	"micro-golang/internal/utils"
+	"net/http"
+	"slices"
+)
+
+/**
+ * @File: oidc_handler.go
+ * @Description:
+ *
+ * OpenID Connect 端點：discovery 與 userinfo，回應格式依 OIDC 規範，不使用 utils.JsonResult
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/20 下午2:50
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// OpenIDConfiguration /.well-known/openid-configuration
+func (h *Handler) OpenIDConfiguration(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, discoveryDocument())
+}
+
+// UserInfo /userinfo，需經過 JWTAuth
+// OAuth token 必須包含 openid scope，並依 profile / email scope 回傳對應 claims；
+// 一般登入的 token 沒有 scope 限制，回傳全部 claims
+func (h *Handler) UserInfo(c *gin.Context) {
+	scopes := oidcScopes
+	if val, ok := c.Get("scopes"); ok {
+		granted, _ := val.([]rbac.Permission)
+		if !slices.Contains(granted, rbac.Permission(ScopeOpenID)) {
+			c.Header("WWW-Authenticate", `Bearer error="insufficient_scope", scope="openid"`)
+			c.JSON(http.StatusForbidden, gin.H{"error": "insufficient_scope"})
+			return
+		}
+		scopes = nil
+		for _, p := range granted {
+			scopes = append(scopes, string(p))
+		}
+	}
+
+	// 以不會變動的 userId 查詢，變更 Email 前發出的 token 仍回傳最新資料
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_token"})
+		return
+	}
+	// 直接查 DB 取得 email_verified（外部登入連結的帳號不一定經過本系統的 Email 驗證），與 ID token 一致
+	var user models.User
+	if err := config.DB.WithContext(c.Request.Context()).First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid_token"})
+			return
+		}
+		log.Printf("❌ 查詢 userinfo 失敗 (user %d)：%v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "server_error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, userClaims(profileOf(user), user.EmailVerifiedAt != nil, scopes))
+}
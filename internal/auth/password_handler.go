@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"log"
+	"micro-golang/internal/dto"
+	"micro-golang/internal/password"
+	"micro-golang/internal/utils"
+)
+
+/**
+ * @File: password_handler.go
+ * @Description:
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/18 下午3:55
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// ForgotPassword 申請重設密碼
+// 不論 Email 是否存在都回傳相同訊息，避免被拿來探測帳號
+func (h *Handler) ForgotPassword(c *gin.Context) {
+	var input dto.PasswordForgotDTO
+	if err := c.ShouldBindJSON(&input); err != nil {
+		var ve validator.ValidationErrors
+		if errors.As(err, &ve) {
+			utils.ReturnError(c, utils.CodeParamInvalid, utils.ExtractFieldErrorMessages(input, ve), "欄位驗證失敗")
+			return
+		}
+		utils.ReturnError(c, utils.CodeParamInvalid, err.Error())
+		return
+	}
+
+	if err := h.authService.ForgotPassword(c, input.Email, c.ClientIP()); err != nil {
+		if errors.Is(err, ErrTooManyRequests) {
+			utils.ReturnError(c, utils.CodeTooManyRequests, nil, "申請次數過多，請稍後再試")
+			return
+		}
+		log.Printf("❌ 申請重設密碼失敗 (%s)：%v", input.Email, err)
+	}
+	utils.ReturnSuccess(c, nil, "若該 Email 已註冊，您將會收到重設密碼信")
+}
+
+// ResetPassword 以重設密碼信中的 token 設定新密碼
+func (h *Handler) ResetPassword(c *gin.Context) {
+	var input dto.PasswordResetDTO
+	if err := c.ShouldBindJSON(&input); err != nil {
+		var ve validator.ValidationErrors
+		if errors.As(err, &ve) {
+			utils.ReturnError(c, utils.CodeParamInvalid, utils.ExtractFieldErrorMessages(input, ve), "欄位驗證失敗")
+			return
+		}
+		utils.ReturnError(c, utils.CodeParamInvalid, err.Error())
+		return
+	}
+
+	if err := h.authService.ResetPassword(c, input.Token, input.Password); err != nil {
+		var policyErr *password.PolicyError
+		if errors.As(err, &policyErr) {
+			utils.ReturnError(c, utils.CodeParamInvalid, policyErr.Messages("password"), "欄位驗證失敗")
+			return
+		}
+		if errors.Is(err, ErrInvalidResetToken) {
+			utils.ReturnError(c, utils.CodeUnauthorized, nil, "重設連結無效或已過期，請重新申請")
+			return
+		}
+		utils.ReturnError(c, utils.CodeServerError, nil, "重設密碼失敗")
+		return
+	}
+	utils.ReturnSuccess(c, nil, "密碼已重設，請以新密碼重新登入")
+}
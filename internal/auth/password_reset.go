@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"log"
+	"micro-golang/internal/config"
+	"micro-golang/internal/mail"
+	"micro-golang/internal/models"
+	"micro-golang/internal/password"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/**
+ * @File: password_reset.go
+ * @Description:
+ *
+ * 忘記密碼流程：寄出一次性、有時效的重設連結，重設後撤銷使用者所有登入
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/18 下午3:40
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+const (
+	passwordResetPrefix      = "password_reset:"      // password_reset:<token hash> → userId
+	passwordResetUserPrefix  = "password_reset:user:" // password_reset:user:<userId> → token hash，確保只有最新一張有效
+	passwordResetTTL         = 30 * time.Minute
+	passwordResetSendTimeout = 30 * time.Second
+
+	// 申請限流：同一 Email 每小時 5 次、同一 IP 每小時 20 次
+	passwordResetEmailLimit = 5
+	passwordResetIPLimit    = 20
+	passwordResetWindow     = time.Hour
+)
+
+var ErrInvalidResetToken = errors.New("invalid or expired reset token")
+
+// ForgotPassword 申請重設密碼，超過限流時回傳 ErrTooManyRequests
+// 通過限流後查詢帳號與寄信都在背景執行，回應時間不會因 Email 是否存在而不同
+func (s *Service) ForgotPassword(ctx context.Context, email string, clientIP string) error {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if ok, err := allow(ctx, "rate:password_reset:email:"+email, passwordResetEmailLimit, passwordResetWindow); err != nil {
+		return err
+	} else if !ok {
+		return ErrTooManyRequests
+	}
+	if ok, err := allow(ctx, "rate:password_reset:ip:"+clientIP, passwordResetIPLimit, passwordResetWindow); err != nil {
+		return err
+	} else if !ok {
+		return ErrTooManyRequests
+	}
+
+	go func() {
+		// 不使用請求的 context，回應送出後仍會繼續
+		ctx, cancel := context.WithTimeout(context.Background(), passwordResetSendTimeout)
+		defer cancel()
+		if err := s.sendPasswordReset(ctx, email); err != nil {
+			log.Printf("❌ 寄送重設密碼信失敗 (%s)：%v", email, err)
+		}
+	}()
+	return nil
+}
+
+// sendPasswordReset 產生重設連結並寄出，Email 不存在時直接回傳 nil
+func (s *Service) sendPasswordReset(ctx context.Context, email string) error {
+	var user models.User
+	if err := config.DB.WithContext(ctx).Where("email = ?", email).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	token, hash := newOpaqueToken()
+	userKey := passwordResetUserPrefix + strconv.FormatUint(uint64(user.ID), 10)
+
+	// 舊的重設連結作廢
+	if oldHash, err := config.RDB.Get(ctx, userKey).Result(); err == nil {
+		config.RDB.Del(ctx, passwordResetPrefix+oldHash)
+	}
+
+	pipe := config.RDB.TxPipeline()
+	pipe.Set(ctx, passwordResetPrefix+hash, user.ID, passwordResetTTL)
+	pipe.Set(ctx, userKey, hash, passwordResetTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "重設密碼",
+		Body: fmt.Sprintf("%s 您好：\n\n請在 %d 分鐘內點擊以下連結重設密碼：\n%s\n\n若您沒有申請重設密碼，請忽略這封信。",
+			user.Username, int(passwordResetTTL.Minutes()), appLink("/reset-password", token)),
+	})
+}
+
+// ResetPassword 以重設 token 設定新密碼
+// 新密碼需符合密碼規則且不可沿用舊密碼；檢查通過後才刪除 token（確保只能使用一次），
+// 密碼不符規則時使用者可以同一個連結再試一次。成功後撤銷使用者所有 session
+func (s *Service) ResetPassword(ctx context.Context, token string, newPassword string) error {
+	tokenKey := passwordResetPrefix + hashToken(token)
+	userIDStr, err := config.RDB.Get(ctx, tokenKey).Result()
+	if errors.Is(err, redis.Nil) {
+		return ErrInvalidResetToken
+	}
+	if err != nil {
+		return err
+	}
+	userID, err := strconv.ParseUint(userIDStr, 10, 64)
+	if err != nil {
+		return ErrInvalidResetToken
+	}
+
+	var user models.User
+	if err := config.DB.WithContext(ctx).First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+	if err := password.Validate(newPassword, user.Email, user.Username); err != nil {
+		return err
+	}
+	if err := password.CheckReuse(ctx, config.DB, user.ID, user.Password, newPassword); err != nil {
+		return err
+	}
+
+	// 同一個連結同時送出兩次時只有一次會成功
+	if n, err := config.RDB.Del(ctx, tokenKey).Result(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrInvalidResetToken
+	}
+	config.RDB.Del(ctx, passwordResetUserPrefix+userIDStr)
+
+	// 密碼已變更，之前發出的所有 token 一律失效
+	return password.Update(ctx, config.DB, user.ID, newPassword)
+}
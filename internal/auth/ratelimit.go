@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"micro-golang/internal/config"
+	"time"
+)
+
+/**
+ * @File: ratelimit.go
+ * @Description:
+ *
+ * 以 Redis 固定時間窗計數的簡易限流
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/18 下午4:40
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+var ErrTooManyRequests = errors.New("too many requests")
+
+// allow 在 window 時間內對 key 計數，超過 limit 回傳 false
+func allow(ctx context.Context, key string, limit int64, window time.Duration) (bool, error) {
+	count, err := config.RDB.Incr(ctx, key).Result()
+	if err != nil {
+		return false, err
+	}
+	// 第一次計數時才設定過期時間，時間窗從第一次請求開始算
+	if count == 1 {
+		if err := config.RDB.Expire(ctx, key, window).Err(); err != nil {
+			return false, err
+		}
+	}
+	return count <= limit, nil
+}
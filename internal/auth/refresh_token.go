@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/redis/go-redis/v9"
+	"log"
+	"micro-golang/internal/blacklist"
+	"micro-golang/internal/config"
+	"micro-golang/internal/middlewares"
+	"micro-golang/internal/models"
+	"micro-golang/internal/session"
+	"micro-golang/internal/tokens"
+	"micro-golang/internal/utils"
+)
+
+/**
+ * @File: refresh_token.go
+ * @Description:
+ *
+ * Refresh Token 輪替與重複使用偵測
+ * 每張 refresh token 帶有 jti 與 family ID，jti 登記在 Redis 並只能被消耗一次；
+ * 若已被消耗的 token 再次出現，代表 token 可能外洩，整個家族會一起撤銷，使用者必須重新登入。
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/18 上午10:20
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+var (
+	ErrRefreshTokenInvalid = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+	ErrRefreshTokenRevoked = errors.New("refresh token family revoked")
+	ErrAccountInactive     = errors.New("account inactive")
+	ErrSessionRevoked      = errors.New("session revoked")
+)
+
+// grantClaims 隨 refresh token 輪替沿用的 OAuth 授權資訊
+var grantClaims = []string{"cid", "scope"}
+
+// issueTokens 簽發新的 access/refresh token，並把 refresh token 的 jti 登記為可用
+// grant 為 OAuth 授權資訊（cid、scope），一般登入傳 nil
+// 必須啟用兩步驟驗證卻尚未設定的帳號只發出限定設定 TOTP 用的 token，每次 refresh 都會重新判斷
+func issueTokens(ctx context.Context, user models.User, familyID string, grant jwt.MapClaims) (string, string, error) {
+	if requiresMFA(user.Role) && !user.TOTPEnabled {
+		restricted := jwt.MapClaims{middlewares.MFASetupClaim: true}
+		for k, v := range grant {
+			restricted[k] = v
+		}
+		grant = restricted
+	}
+	jti := utils.NewTokenID()
+	accessToken, refreshToken, err := utils.GenerateJWT(user.Email, user.ID, user.Role, familyID, jti, grant)
+	if err != nil {
+		return "", "", err
+	}
+
+	// value 存 family ID，消耗時順便比對，避免 jti 被拿到別的家族使用
+	if err := config.RDB.Set(ctx, tokens.RefreshJTIKey(jti), familyID, utils.RefreshTokenTTL).Err(); err != nil {
+		return "", "", err
+	}
+	return accessToken, refreshToken, nil
+}
+
+// consumeRefreshToken 原子性地消耗 refresh token（GETDEL），確保同一張 token 只能換一次新 token
+// 若 jti 已不存在，代表這張 token 之前已被使用過 → 撤銷整個家族
+func consumeRefreshToken(ctx context.Context, jti string, familyID string) error {
+	if blacklist.IsFamilyRevoked(ctx, familyID) {
+		return ErrRefreshTokenRevoked
+	}
+
+	stored, err := config.RDB.GetDel(ctx, tokens.RefreshJTIKey(jti)).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return err
+	}
+	if errors.Is(err, redis.Nil) || stored != familyID {
+		log.Printf("⚠️ refresh token 重複使用，撤銷家族 %s (jti=%s)", familyID, jti)
+		if err := blacklist.RevokeFamily(ctx, familyID); err != nil {
+			return err
+		}
+		return ErrRefreshTokenReused
+	}
+	return nil
+}
+
+// refreshResult refreshTokens 的結果
+type refreshResult struct {
+	User         models.User
+	AccessToken  string
+	RefreshToken string
+	Grant        jwt.MapClaims // 沿用的 OAuth 授權資訊，一般登入為空
+}
+
+// refreshTokens 以 refresh token 換發新的 token（/auth/refresh 與 /oauth/token 共用）
+// clientID 不為空時，refresh token 必須是發給該 OAuth client 的
+func refreshTokens(ctx context.Context, refreshToken string, clientID string, userAgent string, clientIP string) (*refreshResult, error) {
+	// 解析 token，並確認是 refresh token
+	claims, err := utils.ParseToken(refreshToken)
+	if err != nil || claims["token_type"] != "refresh" {
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	// 檢查Redis 是否存在黑名單（已登出），或在變更密碼之前發出
+	if blacklist.IsRefreshTokenBlacklisted(ctx, refreshToken) || tokens.IssuedBeforeRevocation(ctx, claims) {
+		return nil, ErrRefreshTokenRevoked
+	}
+
+	// 以 userId 識別使用者，變更 Email 後 refresh token 仍然有效
+	userID, ok := utils.ClaimUserID(claims)
+	jti, jtiOk := claims["jti"].(string)
+	familyID, fidOk := claims["fid"].(string)
+	if !ok || !jtiOk || !fidOk {
+		return nil, ErrRefreshTokenInvalid
+	}
+	if cid, _ := claims["cid"].(string); clientID != "" && cid != clientID {
+		return nil, ErrRefreshTokenInvalid
+	}
+
+	// 消耗這張 refresh token，重複使用會撤銷整個家族
+	if err := consumeRefreshToken(ctx, jti, familyID); err != nil {
+		return nil, err
+	}
+
+	// 查詢使用者資料
+	var user models.User
+	if err := config.DB.WithContext(ctx).First(&user, userID).Error; err != nil {
+		return nil, ErrRefreshTokenInvalid
+	}
+	if !user.IsActive {
+		_ = session.Revoke(ctx, user.ID, familyID)
+		return nil, ErrAccountInactive
+	}
+
+	// 更新 session 最後使用時間；若 session 已被撤銷則不再發新 token
+	if err := session.Touch(ctx, user.ID, familyID, userAgent, clientIP); err != nil {
+		return nil, ErrSessionRevoked
+	}
+
+	grant := jwt.MapClaims{}
+	for _, k := range grantClaims {
+		if v, ok := claims[k]; ok {
+			grant[k] = v
+		}
+	}
+
+	// 產生新 token（沿用同一個家族）
+	accessToken, newRefreshToken, err := issueTokens(ctx, user, familyID, grant)
+	if err != nil {
+		return nil, err
+	}
+	return &refreshResult{User: user, AccessToken: accessToken, RefreshToken: newRefreshToken, Grant: grant}, nil
+}
@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"micro-golang/internal/config"
+)
+
+/**
+ * @File: secretbox.go
+ * @Description:
+ *
+ * 以 AES-256-GCM 加密存放在資料庫中的機敏資料（例如 TOTP 金鑰）
+ * 金鑰來自 MFA_ENCRYPTION_KEY（base64 編碼的 32 bytes），資料庫外洩時無法直接還原金鑰
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/19 上午10:05
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+var ErrEncryptionKeyMissing = errors.New("MFA_ENCRYPTION_KEY is not configured")
+
+func secretCipher() (cipher.AEAD, error) {
+	raw := config.GetEnv("MFA_ENCRYPTION_KEY", "")
+	if raw == "" {
+		return nil, ErrEncryptionKeyMissing
+	}
+	key, err := base64.StdEncoding.DecodeString(raw)
+	if err != nil || len(key) != 32 {
+		return nil, errors.New("MFA_ENCRYPTION_KEY must be 32 bytes base64")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptSecret 加密字串，輸出為 base64(nonce + ciphertext)
+func encryptSecret(plain string) (string, error) {
+	aead, err := secretCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	_, _ = rand.Read(nonce)
+	sealed := aead.Seal(nonce, nonce, []byte(plain), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptSecret 解密 encryptSecret 的輸出
+func decryptSecret(encoded string) (string, error) {
+	aead, err := secretCipher()
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < aead.NonceSize() {
+		return "", errors.New("ciphertext too short")
+	}
+	plain, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
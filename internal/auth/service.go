@@ -2,10 +2,13 @@ package auth
 
 import (
 	"github.com/gin-gonic/gin"
-	"golang.org/x/crypto/bcrypt"
+	"log"
 	"micro-golang/internal/config"
 	"micro-golang/internal/dto"
+	"micro-golang/internal/mail"
 	"micro-golang/internal/models"
+	"micro-golang/internal/password"
+	"micro-golang/internal/rbac"
 	"micro-golang/internal/utils"
 )
 
@@ -20,28 +23,31 @@ import (
  */
 
 // Service encapsulates authentication logic
-type Service struct{}
+type Service struct {
+	mailer mail.Mailer         // 寄送重設密碼等通知信
+	idps   []*IdentityProvider // 可用來登入的外部身分提供者
+}
 
 // NewService creates a new AuthService instance
-func NewService() *Service {
-	return &Service{}
+func NewService(mailer mail.Mailer, idps []*IdentityProvider) *Service {
+	return &Service{mailer: mailer, idps: idps}
 }
 
 // Register 直接在 Service 呼叫 utils 返回 JSON，無需回傳任何參數
 func (s *Service) Register(c *gin.Context, input dto.UserRegisterDTO) {
 	// 1. 密碼雜湊
-	hashed, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	hashed, err := password.Hash(input.Password)
 	if err != nil {
 		utils.ReturnError(c, utils.CodeServerError, nil, "密碼加密失敗")
 		return
 	}
 
-	// 2. 準備用戶實體
+	// 2. 準備用戶實體，公開註冊一律為一般使用者，其他角色只能由管理者指派
 	user := models.User{
 		Email:    input.Email,
 		Username: input.Username,
-		Password: string(hashed),
-		Role:     input.Role,
+		Password: hashed,
+		Role:     rbac.RoleUser,
 	}
 
 	// 3. 寫入資料庫
@@ -50,8 +56,17 @@ func (s *Service) Register(c *gin.Context, input dto.UserRegisterDTO) {
 		utils.ReturnError(c, utils.CodeEmailExists, nil, "該用戶已存在")
 		return
 	}
+	// 記錄密碼歷史，之後重設密碼時不可沿用
+	if err := password.Remember(config.DB, user.ID, hashed); err != nil {
+		log.Printf("❌ 記錄密碼歷史失敗 (%s)：%v", user.Email, err)
+	}
+
+	// 4. 寄出驗證信，驗證完成前無法登入；寄送失敗時使用者可再申請重寄
+	if err := s.SendVerificationEmail(c, user); err != nil {
+		log.Printf("❌ 寄送驗證信失敗 (%s)：%v", user.Email, err)
+	}
 
-	// 4. 組裝 DTO
+	// 5. 組裝 DTO
 	resp := dto.UserLoginResponseDTO{
 		ID:       user.ID,
 		Email:    user.Email,
@@ -59,6 +74,6 @@ func (s *Service) Register(c *gin.Context, input dto.UserRegisterDTO) {
 		Role:     user.Role,
 	}
 
-	// 5. 回傳成功 JSON
-	utils.ReturnSuccess(c, resp, user.Email+" :註冊成功")
+	// 6. 回傳成功 JSON
+	utils.ReturnSuccess(c, resp, user.Email+" :註冊成功，請至信箱完成 Email 驗證")
 }
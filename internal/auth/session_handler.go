@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"micro-golang/internal/dto"
+	"micro-golang/internal/session"
+	"micro-golang/internal/utils"
+)
+
+/**
+ * @File: session_handler.go
+ * @Description:
+ *
+ * 登入裝置（Session）管理 API，需經過 JWTAuth
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/18 上午11:40
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// ListSessions 列出目前使用者所有登入中的裝置
+func (h *Handler) ListSessions(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		utils.ReturnError(c, utils.CodeUnauthorized, nil, "Token 內容無效")
+		return
+	}
+
+	sessions, err := session.List(c, userID)
+	if err != nil {
+		utils.ReturnError(c, utils.CodeServerError, nil, "無法取得登入裝置")
+		return
+	}
+
+	currentID := c.GetString("sessionId")
+	result := make([]dto.SessionDTO, 0, len(sessions))
+	for _, s := range sessions {
+		result = append(result, dto.SessionDTO{
+			ID:         s.ID,
+			UserAgent:  s.UserAgent,
+			IP:         s.IP,
+			CreatedAt:  s.CreatedAt,
+			LastUsedAt: s.LastUsedAt,
+			Current:    s.ID == currentID,
+		})
+	}
+	utils.ReturnSuccess(c, result)
+}
+
+// RevokeSession 登出指定裝置
+func (h *Handler) RevokeSession(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		utils.ReturnError(c, utils.CodeUnauthorized, nil, "Token 內容無效")
+		return
+	}
+
+	if err := session.Revoke(c, userID, c.Param("id")); err != nil {
+		if errors.Is(err, session.ErrNotFound) {
+			utils.ReturnError(c, utils.CodeNotFound, nil, "找不到該登入裝置")
+			return
+		}
+		utils.ReturnError(c, utils.CodeServerError, nil, "登出裝置失敗")
+		return
+	}
+	utils.ReturnSuccess(c, nil, "Session revoked")
+}
+
+// RevokeAllSessions 登出所有裝置（包含目前這個）
+func (h *Handler) RevokeAllSessions(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		utils.ReturnError(c, utils.CodeUnauthorized, nil, "Token 內容無效")
+		return
+	}
+
+	count, err := session.RevokeAll(c, userID)
+	if err != nil {
+		utils.ReturnError(c, utils.CodeServerError, nil, "登出所有裝置失敗")
+		return
+	}
+	utils.ReturnSuccess(c, gin.H{"revoked": count}, "All sessions revoked")
+}
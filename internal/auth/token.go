@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"micro-golang/internal/config"
+	"net/url"
+)
+
+/**
+ * @File: token.go
+ * @Description:
+ *
+ * 一次性 token（重設密碼、Email 驗證等）共用工具
+ * 原始 token 只出現在寄給使用者的連結中，Redis 只保存雜湊值，資料外洩時無法直接拿來使用。
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/18 下午3:30
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// newOpaqueToken 產生隨機 token，回傳原始值與其雜湊值
+func newOpaqueToken() (string, string) {
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token, hashToken(token)
+}
+
+// hashToken 計算 token 的 SHA-256 雜湊值（token 本身已是高熵亂數，不需要加鹽）
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// appLink 組出前端頁面連結，APP_BASE_URL 為前端網址
+func appLink(path string, token string) string {
+	base := config.GetEnv("APP_BASE_URL", "http://localhost:5173")
+	return base + path + "?token=" + url.QueryEscape(token)
+}
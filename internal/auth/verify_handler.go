@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"log"
+	"micro-golang/internal/dto"
+	"micro-golang/internal/user"
+	"micro-golang/internal/utils"
+)
+
+/**
+ * @File: verify_handler.go
+ * @Description:
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/18 下午5:05
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// VerifyEmail 點擊驗證信連結完成 Email 驗證（GET /auth/verify?token=）
+func (h *Handler) VerifyEmail(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		utils.ReturnError(c, utils.CodeParamInvalid, nil, "請提供 token")
+		return
+	}
+
+	user, err := h.authService.VerifyEmail(c, token)
+	if err != nil {
+		if errors.Is(err, ErrInvalidVerifyToken) {
+			utils.ReturnError(c, utils.CodeUnauthorized, nil, "驗證連結無效或已過期，請重新申請驗證信")
+			return
+		}
+		utils.ReturnError(c, utils.CodeServerError, nil, "Email 驗證失敗")
+		return
+	}
+	utils.ReturnSuccess(c, dto.UserLoginResponseDTO{
+		ID:       user.ID,
+		Email:    user.Email,
+		Username: user.Username,
+		Role:     user.Role,
+	}, "Email 驗證成功，現在可以登入了")
+}
+
+// ConfirmEmailChange 點擊寄到新 Email 的確認連結完成變更（GET /auth/email-change/confirm?token=）
+// 已登入的 session 不受影響，下次 refresh 取得的 token 就會帶新 Email
+func (h *Handler) ConfirmEmailChange(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		utils.ReturnError(c, utils.CodeParamInvalid, nil, "請提供 token")
+		return
+	}
+
+	profile, err := h.userService.ConfirmEmailChange(c.Request.Context(), token)
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrInvalidEmailChangeToken):
+			utils.ReturnError(c, utils.CodeUnauthorized, nil, "確認連結無效或已過期，請重新申請變更 Email")
+		case errors.Is(err, user.ErrEmailInUse):
+			utils.ReturnError(c, utils.CodeEmailExists, nil, "此 Email 已被其他帳號使用")
+		default:
+			utils.ReturnError(c, utils.CodeServerError, nil, "Email 變更失敗")
+		}
+		return
+	}
+	utils.ReturnSuccess(c, profile, "Email 已變更，請使用新 Email 登入")
+}
+
+// ResendVerification 重寄驗證信（有限流）
+func (h *Handler) ResendVerification(c *gin.Context) {
+	var input dto.VerifyResendDTO
+	if err := c.ShouldBindJSON(&input); err != nil {
+		var ve validator.ValidationErrors
+		if errors.As(err, &ve) {
+			utils.ReturnError(c, utils.CodeParamInvalid, utils.ExtractFieldErrorMessages(input, ve), "欄位驗證失敗")
+			return
+		}
+		utils.ReturnError(c, utils.CodeParamInvalid, err.Error())
+		return
+	}
+
+	if err := h.authService.ResendVerification(c, input.Email, c.ClientIP()); err != nil {
+		if errors.Is(err, ErrTooManyRequests) {
+			utils.ReturnError(c, utils.CodeTooManyRequests, nil, "重寄次數過多，請稍後再試")
+			return
+		}
+		log.Printf("❌ 重寄驗證信失敗 (%s)：%v", input.Email, err)
+	}
+	utils.ReturnSuccess(c, nil, "若該 Email 已註冊且尚未驗證，您將會收到驗證信")
+}
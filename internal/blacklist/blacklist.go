@@ -0,0 +1,85 @@
+package blacklist
+
+import (
+	"context"
+	"micro-golang/internal/config"
+	"micro-golang/internal/utils"
+	"strconv"
+	"time"
+)
+
+/**
+ * @File: blacklist.go
+ * @Description:
+ *
+ * 集中管理 Redis 上的 Token 黑名單 key，避免各服務各自拼字串
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/18 上午10:12
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+const (
+	accessTokenPrefix  = "blacklist:access_token:"
+	refreshTokenPrefix = "blacklist:refresh_token:"
+	familyPrefix       = "blacklist:refresh_family:"
+	userBeforePrefix   = "blacklist:user_tokens_before:" // blacklist:user_tokens_before:<userId> → unix 秒
+)
+
+// AddAccessToken 將 access token 放進黑名單，直到 token 原本的過期時間
+func AddAccessToken(ctx context.Context, token string, exp time.Time) error {
+	return setUntil(ctx, accessTokenPrefix+token, exp)
+}
+
+// IsAccessTokenBlacklisted 檢查 access token 是否已被登出
+func IsAccessTokenBlacklisted(ctx context.Context, token string) bool {
+	return exists(ctx, accessTokenPrefix+token)
+}
+
+// AddRefreshToken 將 refresh token 放進黑名單，直到 token 原本的過期時間
+func AddRefreshToken(ctx context.Context, token string, exp time.Time) error {
+	return setUntil(ctx, refreshTokenPrefix+token, exp)
+}
+
+// IsRefreshTokenBlacklisted 檢查 refresh token 是否已被登出
+func IsRefreshTokenBlacklisted(ctx context.Context, token string) bool {
+	return exists(ctx, refreshTokenPrefix+token)
+}
+
+// RevokeFamily 撤銷整個 refresh token 家族（同一次登入輪替出來的所有 token）
+// 家族內最新的 token 最晚在 RefreshTokenTTL 後過期，所以黑名單保留同樣長度即可
+func RevokeFamily(ctx context.Context, familyID string) error {
+	return config.RDB.Set(ctx, familyPrefix+familyID, "1", utils.RefreshTokenTTL).Err()
+}
+
+// IsFamilyRevoked 檢查 refresh token 家族是否已被撤銷
+func IsFamilyRevoked(ctx context.Context, familyID string) bool {
+	return exists(ctx, familyPrefix+familyID)
+}
+
+// RevokeUserTokensBefore 使用者在 t 之前發出的所有 token 一律失效（變更密碼時使用）
+// 這些 token 最晚在 RefreshTokenTTL 後過期，所以紀錄保留同樣長度即可
+func RevokeUserTokensBefore(ctx context.Context, userID uint, t time.Time) error {
+	return config.RDB.Set(ctx, userBeforePrefix+strconv.FormatUint(uint64(userID), 10), t.Unix(), utils.RefreshTokenTTL).Err()
+}
+
+// UserTokensRevokedBefore 使用者 token 的最早有效發出時間（unix 秒），沒有紀錄時回傳 0
+func UserTokensRevokedBefore(ctx context.Context, userID uint) int64 {
+	n, _ := config.RDB.Get(ctx, userBeforePrefix+strconv.FormatUint(uint64(userID), 10)).Int64()
+	return n
+}
+
+// setUntil 設定 key 並讓它在 exp 時自動失效，已過期的 token 不必再記錄
+func setUntil(ctx context.Context, key string, exp time.Time) error {
+	ttl := time.Until(exp)
+	if ttl <= 0 {
+		return nil
+	}
+	return config.RDB.Set(ctx, key, "1", ttl).Err()
+}
+
+func exists(ctx context.Context, key string) bool {
+	n, _ := config.RDB.Exists(ctx, key).Result()
+	return n == 1
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+/**
+ * @File: env.go
+ * @Description:
+ *
+ * 讀取環境變數並提供預設值
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/18 下午1:10
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// GetEnv 讀取字串環境變數，未設定時回傳預設值
+func GetEnv(key string, def string) string {
+	if val := os.Getenv(key); val != "" {
+		return val
+	}
+	return def
+}
+
+// GetEnvInt 讀取整數環境變數，未設定或格式錯誤時回傳預設值
+func GetEnvInt(key string, def int) int {
+	val := os.Getenv(key)
+	if val == "" {
+		return def
+	}
+	n, err := strconv.Atoi(val)
+	if err != nil {
+		log.Printf("⚠️ 環境變數 %s=%q 不是整數，改用預設值 %d", key, val, def)
+		return def
+	}
+	return n
+}
+
+// GetEnvDuration 讀取時間長度環境變數（例如 15m、24h），未設定或格式錯誤時回傳預設值
+func GetEnvDuration(key string, def time.Duration) time.Duration {
+	val := os.Getenv(key)
+	if val == "" {
+		return def
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		log.Printf("⚠️ 環境變數 %s=%q 不是合法的時間長度，改用預設值 %s", key, val, def)
+		return def
+	}
+	return d
+}
+
+// GetEnvBool 讀取布林環境變數，未設定或格式錯誤時回傳預設值
+func GetEnvBool(key string, def bool) bool {
+	val := os.Getenv(key)
+	if val == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(val)
+	if err != nil {
+		log.Printf("⚠️ 環境變數 %s=%q 不是布林值，改用預設值 %t", key, val, def)
+		return def
+	}
+	return b
+}
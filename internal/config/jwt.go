@@ -0,0 +1,58 @@
+package config
+
+import (
+	"log"
+	"micro-golang/internal/utils"
+	"time"
+)
+
+/**
+ * @File: jwt.go
+ * @Description:
+ *
+ * JWT 簽章 / 驗章初始化
+ * authsvc 持有私鑰負責簽章並公開 JWKS；usersvc、ordersvc 只透過 JWKS 取得公鑰驗章，不再共用密鑰。
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/18 下午2:15
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// InitJWTSigner 載入 authsvc 的簽章金鑰並啟動定期輪替
+//
+// JWT_KEY_DIR       金鑰目錄（預設 keys）
+// JWT_SIGNING_ALG   新金鑰演算法 RS256 / EdDSA（預設 RS256）
+// JWT_KEY_ROTATION  多久換一把新金鑰（預設 720h）
+// JWT_KEY_OVERLAP   舊金鑰退役後仍可驗章的時間（預設與 refresh token 壽命相同）
+func InitJWTSigner() {
+	dir := GetEnv("JWT_KEY_DIR", "keys")
+	alg := GetEnv("JWT_SIGNING_ALG", "RS256")
+	rotation := GetEnvDuration("JWT_KEY_ROTATION", 30*24*time.Hour)
+	overlap := GetEnvDuration("JWT_KEY_OVERLAP", utils.RefreshTokenTTL)
+
+	// overlap 比 token 壽命短的話，輪替後還沒過期的 token 會突然驗不過
+	if overlap < utils.RefreshTokenTTL {
+		log.Printf("⚠️ JWT_KEY_OVERLAP (%s) 小於 refresh token 壽命，改用 %s", overlap, utils.RefreshTokenTTL)
+		overlap = utils.RefreshTokenTTL
+	}
+
+	ks, err := utils.LoadKeySet(dir, alg, rotation, overlap)
+	if err != nil {
+		log.Fatalf("❌ 載入 JWT 簽章金鑰失敗：%v", err)
+	}
+	ks.StartRotation(Ctx)
+	utils.UseSigningKeys(ks)
+	log.Printf("✅ JWT 簽章金鑰已載入 (kid=%s)", ks.Active().ID)
+}
+
+// InitJWTVerifier 透過 authsvc 公開的 JWKS 驗證 token
+//
+// AUTH_JWKS_URL   JWKS 位址（預設 http://localhost:7001/.well-known/jwks.json）
+// JWKS_CACHE_TTL  JWKS 快取時間（預設 5m）
+func InitJWTVerifier() {
+	url := GetEnv("AUTH_JWKS_URL", "http://localhost:7001/.well-known/jwks.json")
+	ttl := GetEnvDuration("JWKS_CACHE_TTL", 5*time.Minute)
+	utils.UseJWKS(utils.NewJWKSClient(url, ttl))
+	log.Printf("✅ JWT 驗章使用 JWKS：%s", url)
+}
@@ -0,0 +1,37 @@
+package dto
+
+import "time"
+
+/**
+ * @File: api_key_dto.go
+ * @Description:
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/19 下午6:05
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// APIKeyCreateDTO 建立 API 金鑰
+type APIKeyCreateDTO struct {
+	Name      string     `json:"name" binding:"required,max=64" validateMsg:"required=名稱為必填,max=名稱最多 64 字" example:"nightly-report"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1,dive,required" validateMsg:"required=scopes 為必填,min=至少需指定一個 scope" example:"order:read"`
+	ExpiresAt *time.Time `json:"expires_at" example:"2027-01-01T00:00:00Z"` // 不填表示永不過期
+}
+
+// APIKeyDTO API 金鑰資訊（不含金鑰本身）
+type APIKeyDTO struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// APIKeyCreatedDTO 建立成功時回傳，Key 只會出現這一次
+type APIKeyCreatedDTO struct {
+	APIKeyDTO
+	Key string `json:"key"`
+}
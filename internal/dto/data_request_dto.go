@@ -0,0 +1,41 @@
+package dto
+
+import (
+	"time"
+)
+
+/**
+ * @File: data_request_dto.go
+ * @Description:
+ *
+ * 個資匯出 / 刪除請求（GDPR data subject request）
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/24 上午10:30
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// DataExportDTO 匯出個資，format 預設 zip（每個類別一個 JSON 檔），json 為單一 JSON 檔
+type DataExportDTO struct {
+	Format string `json:"format" binding:"omitempty,oneof=json zip" validateMsg:"oneof=format 只能是 json 或 zip"`
+}
+
+// DataEraseDTO 刪除個資，需再次輸入密碼
+type DataEraseDTO struct {
+	CurrentPassword string `json:"current_password" binding:"required" validateMsg:"required=目前密碼為必填"`
+}
+
+// DataRequestDTO 個資匯出 / 刪除工作的狀態
+type DataRequestDTO struct {
+	ID         string     `json:"id"`
+	UserID     uint       `json:"user_id"`
+	Type       string     `json:"type"`             // export / erase
+	Format     string     `json:"format,omitempty"` // 匯出格式 json / zip
+	Status     string     `json:"status"`           // pending / running / done / failed
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	// ExpiresAt 匯出檔可下載的期限
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
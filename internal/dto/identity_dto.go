@@ -0,0 +1,18 @@
+package dto
+
+/**
+ * @File: identity_dto.go
+ * @Description:
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/21 上午11:40
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// IdentityProviderDTO 可用來登入的外部身分提供者
+type IdentityProviderDTO struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	LoginURL    string `json:"login_url"` // 瀏覽器直接導向此網址開始登入
+}
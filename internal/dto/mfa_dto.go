@@ -0,0 +1,42 @@
+package dto
+
+/**
+ * @File: mfa_dto.go
+ * @Description:
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/19 上午10:50
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// TOTPSetupDTO 設定 TOTP 時回傳給前端的資訊，復原碼只會顯示這一次
+type TOTPSetupDTO struct {
+	OtpauthURI    string   `json:"otpauth_uri"`
+	Secret        string   `json:"secret"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// TOTPConfirmDTO 確認 TOTP 設定
+type TOTPConfirmDTO struct {
+	Code string `json:"code" binding:"required,len=6,numeric" validateMsg:"required=驗證碼為必填,len=驗證碼為 6 位數字,numeric=驗證碼為 6 位數字" example:"123456"`
+}
+
+// TOTPDisableDTO 停用兩步驟驗證，需再次輸入密碼與驗證碼（或復原碼）
+type TOTPDisableDTO struct {
+	CurrentPassword string `json:"current_password" binding:"required" validateMsg:"required=目前密碼為必填"`
+	Code            string `json:"code" binding:"required" validateMsg:"required=請輸入驗證碼或復原碼" example:"123456"`
+}
+
+// MFAChallengeDTO 密碼正確但需要兩步驟驗證時的回應
+type MFAChallengeDTO struct {
+	MFARequired    bool   `json:"mfa_required"`
+	ChallengeToken string `json:"challenge_token"`
+	ExpiresIn      int    `json:"expires_in"` // 秒
+}
+
+// MFAVerifyDTO 以 challenge token 搭配 TOTP 驗證碼或復原碼完成登入
+type MFAVerifyDTO struct {
+	ChallengeToken string `json:"challenge_token" binding:"required" validateMsg:"required=challenge_token 為必填"`
+	Code           string `json:"code" binding:"required" validateMsg:"required=請輸入驗證碼或復原碼" example:"123456"`
+}
@@ -0,0 +1,73 @@
+package dto
+
+/**
+ * @File: oauth_dto.go
+ * @Description:
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/20 上午9:40
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// OAuthClientCreateDTO 註冊 OAuth client
+type OAuthClientCreateDTO struct {
+	Name         string   `json:"name" binding:"required,max=128" validateMsg:"required=名稱為必填,max=名稱最多 128 字" example:"GitHub Pages SPA"`
+	RedirectURIs []string `json:"redirect_uris" binding:"dive,url" validateMsg:"url=redirect_uri 格式錯誤" example:"https://taguo1109.github.io/callback"`
+	Scopes       []string `json:"scopes" binding:"required,min=1" validateMsg:"required=scopes 為必填,min=至少需指定一個 scope" example:"profile:read"`
+	GrantTypes   []string `json:"grant_types" binding:"required,min=1,dive,oneof=authorization_code refresh_token client_credentials" validateMsg:"required=grant_types 為必填,min=至少需指定一個 grant type,oneof=grant type 只能是 authorization_code、refresh_token 或 client_credentials" example:"authorization_code"`
+	// Public 無法保存密鑰的 client（SPA、行動 App）
+	Public bool `json:"public" example:"true"`
+	// FirstParty 自家的資源伺服器，可撤銷一般登入的 token（需為 confidential client）
+	FirstParty bool `json:"first_party" example:"false"`
+	// ServiceRole client_credentials 取得的 token 所代表的角色
+	ServiceRole string `json:"service_role" binding:"omitempty,oneof=User Admin" validateMsg:"oneof=service_role 只能是 User 或 Admin" example:"User"`
+}
+
+// OAuthClientCreatedDTO 註冊成功時回傳，ClientSecret 只會出現這一次（public client 沒有）
+type OAuthClientCreatedDTO struct {
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret,omitempty"`
+}
+
+// OAuthTokenResponse /oauth/token 的回應（RFC 6749 5.1）
+type OAuthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+	IDToken      string `json:"id_token,omitempty"` // scope 含 openid 時才有
+}
+
+// OAuthErrorResponse /oauth/token 的錯誤回應（RFC 6749 5.2）
+type OAuthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// IntrospectionResponse /auth/introspect 的回應（RFC 7662 2.2），token 無效時只有 active=false
+type IntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	Jti       string `json:"jti,omitempty"`
+	// 以下為本系統的擴充欄位
+	UserID    uint   `json:"user_id,omitempty"`
+	Role      string `json:"role,omitempty"`
+	SessionID string `json:"session_id,omitempty"`
+	// Act 代理登入的 token 才有，記錄實際操作的管理者（RFC 8693 4.1）
+	Act *IntrospectionActor `json:"act,omitempty"`
+}
+
+// IntrospectionActor 代理登入時實際操作的管理者
+type IntrospectionActor struct {
+	Sub   string `json:"sub"`
+	Email string `json:"email,omitempty"`
+	Role  string `json:"role,omitempty"`
+}
@@ -0,0 +1,23 @@
+package dto
+
+import "time"
+
+/**
+ * @File: session_dto.go
+ * @Description:
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/18 上午11:32
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// SessionDTO 使用者登入裝置資訊
+type SessionDTO struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	Current    bool      `json:"current"` // 是否為目前發出請求的這個 session
+}
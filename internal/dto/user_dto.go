@@ -1,5 +1,10 @@
 package dto
 
+import (
+	"micro-golang/internal/utils"
+	"time"
+)
+
 /**
  * @File: user_dto.go
  * @Description:
@@ -11,12 +16,17 @@ package dto
  */
 
 type UserDTO struct {
-	ID           uint   `json:"id"`
-	Email        string `json:"email"`
-	Username     string `json:"username"`
-	Role         string `json:"role"`
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
+	ID       uint   `json:"id"`
+	Email    string `json:"email"`
+	Username string `json:"username"`
+	Role     string `json:"role"`
+	// Cookie 登入模式下 token 只放在 HttpOnly cookie，回應中不會出現
+	AccessToken  string `json:"access_token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	// CSRFToken Cookie 登入模式下，狀態變更的請求需放在 X-CSRF-Token header
+	CSRFToken string `json:"csrf_token,omitempty"`
+	// MFASetupRequired 此角色必須啟用兩步驟驗證但尚未設定
+	MFASetupRequired bool `json:"mfa_setup_required,omitempty"`
 }
 
 type UserLoginDTO struct {
@@ -30,6 +40,19 @@ type UserLoginResponseDTO struct {
 	Role     string `json:"role"`
 }
 
+// UserProfileUpdateResponseDTO 更新個人資料的回應
+// PendingEmail 已寄出確認信、尚未生效的新 Email；確認前 Email 仍是原本的值
+type UserProfileUpdateResponseDTO struct {
+	UserLoginResponseDTO
+	PendingEmail string `json:"pending_email,omitempty"`
+}
+
+// UserEmailDTO /users/email/:id 只回傳 Email
+type UserEmailDTO struct {
+	ID    uint   `json:"id"`
+	Email string `json:"email"`
+}
+
 type UserLogoutDTO struct {
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
@@ -38,13 +61,110 @@ type UserLogoutDTO struct {
 type UserRegisterDTO struct {
 	Email    string `json:"email" binding:"required,email" validateMsg:"required=Email 為必填,email=Email 格式錯誤" example:"test@example.com"`
 	Username string `json:"username" binding:"required,username_validation" validateMsg:"required=使用者名稱為必填,username_validation=使用者名稱只能是英文與數字，且長度為 6~20 字" example:"testUser01"`
-	Password string `json:"password" binding:"required,pwd_validation" validateMsg:"required=密碼為必填,pwd_validation=密碼需包含至少一個大寫與一個小寫字母，且長度 6~30 字" example:"P@ssw0rd"`
+	Password string `json:"password" binding:"required,pwd_length,pwd_charset,pwd_classes,pwd_personal,pwd_breached" validateMsg:"required=密碼為必填" example:"P@ssw0rd"`
+}
+
+// AdminCreateUserDTO 管理者建立帳號，可指定角色（只能指定比自己低階的角色）
+type AdminCreateUserDTO struct {
+	Email    string `json:"email" binding:"required,email" validateMsg:"required=Email 為必填,email=Email 格式錯誤" example:"test@example.com"`
+	Username string `json:"username" binding:"required,username_validation" validateMsg:"required=使用者名稱為必填,username_validation=使用者名稱只能是英文與數字，且長度為 6~20 字" example:"testUser01"`
+	Password string `json:"password" binding:"required,pwd_length,pwd_charset,pwd_classes,pwd_personal,pwd_breached" validateMsg:"required=密碼為必填" example:"P@ssw0rd"`
 	Role     string `json:"role" binding:"required,oneof=User Admin SuperAdmin" validateMsg:"required=角色為必填,oneof=角色只能是 User、Admin 或 SuperAdmin" example:"User"`
 }
 
+// AdminChangeRoleDTO 管理者調整使用者角色
+type AdminChangeRoleDTO struct {
+	Role string `json:"role" binding:"required,oneof=User Admin SuperAdmin" validateMsg:"required=角色為必填,oneof=角色只能是 User、Admin 或 SuperAdmin" example:"Admin"`
+}
+
 // UserUpdateProfileDTO defines the fields for updating a user's profile.
 // Using pointers to allow partial updates (fields not provided will not be changed).
 type UserUpdateProfileDTO struct {
 	Username *string `json:"username" example:"newAwesomeUser"`     // Optional: new username
 	Email    *string `json:"email" example:"new.email@example.com"` // Optional: new email
+	// 變更 Email 時必填，需再次確認目前密碼
+	CurrentPassword string `json:"current_password"`
+}
+
+// PasswordForgotDTO 申請重設密碼
+type PasswordForgotDTO struct {
+	Email string `json:"email" binding:"required,email" validateMsg:"required=Email 為必填,email=Email 格式錯誤" example:"test@example.com"`
+}
+
+// PasswordResetDTO 以重設密碼信中的 token 設定新密碼
+// 密碼規則由 service 以 password.Validate 檢查，所有不符合的規則會一次回傳
+type PasswordResetDTO struct {
+	Token    string `json:"token" binding:"required" validateMsg:"required=token 為必填"`
+	Password string `json:"password" binding:"required" validateMsg:"required=密碼為必填" example:"P@ssw0rd"`
+}
+
+// PasswordChangeDTO 登入後變更密碼
+// 密碼規則由 service 以 password.Validate 檢查，所有不符合的規則會一次回傳
+type PasswordChangeDTO struct {
+	CurrentPassword string `json:"current_password" binding:"required" validateMsg:"required=目前密碼為必填"`
+	Password        string `json:"password" binding:"required" validateMsg:"required=密碼為必填" example:"N3w-P@ssw0rd"`
+}
+
+// AccountDeleteDTO 刪除自己的帳號，需再次輸入密碼
+type AccountDeleteDTO struct {
+	CurrentPassword string `json:"current_password" binding:"required" validateMsg:"required=目前密碼為必填"`
+}
+
+// VerifyResendDTO 重寄 Email 驗證信
+type VerifyResendDTO struct {
+	Email string `json:"email" binding:"required,email" validateMsg:"required=Email 為必填,email=Email 格式錯誤" example:"test@example.com"`
+}
+
+// MagicLinkDTO 申請免密碼登入連結
+type MagicLinkDTO struct {
+	Email string `json:"email" binding:"required,email" validateMsg:"required=Email 為必填,email=Email 格式錯誤" example:"test@example.com"`
+}
+
+// AdminUnlockLoginDTO 管理者解除登入鎖定，Email 與 IP 至少填一個
+type AdminUnlockLoginDTO struct {
+	Email string `json:"email" binding:"omitempty,email" validateMsg:"email=Email 格式錯誤" example:"test@example.com"`
+	IP    string `json:"ip" binding:"omitempty,ip" validateMsg:"ip=IP 格式錯誤" example:"203.0.113.10"`
+}
+
+// ImpersonateDTO 管理者代理登入
+type ImpersonateDTO struct {
+	Reason string `json:"reason" binding:"required,max=255" validateMsg:"required=請填寫代理原因,max=代理原因最多 255 字" example:"重現客訴 #1234"`
+}
+
+// ImpersonationTokenDTO 代理登入發出的 token
+type ImpersonationTokenDTO struct {
+	AccessToken string               `json:"access_token"`
+	TokenType   string               `json:"token_type"`
+	ExpiresIn   int                  `json:"expires_in"` // 秒
+	User        UserLoginResponseDTO `json:"user"`       // 被代理的使用者
+}
+
+// AdminUserListQuery 管理者查詢使用者列表（GET /admin/users）
+// email / username 為前綴比對；created_from、created_to 為 RFC3339 或 YYYY-MM-DD（只給日期時 created_to 包含當天）；
+// sort 為欄位名稱，加上 - 代表由大到小，預設 -created_at；
+// deleted 預設不含已刪除的帳號，true 為一併列出，only 為只列出已刪除（寬限期內可復原）的帳號
+type AdminUserListQuery struct {
+	utils.PageRequest
+	Role        string `json:"role" form:"role" binding:"omitempty,oneof=User Admin SuperAdmin" validateMsg:"oneof=角色只能是 User、Admin 或 SuperAdmin"`
+	IsActive    *bool  `json:"is_active" form:"is_active"`
+	CreatedFrom string `json:"created_from" form:"created_from"`
+	CreatedTo   string `json:"created_to" form:"created_to"`
+	Email       string `json:"email" form:"email"`
+	Username    string `json:"username" form:"username"`
+	Deleted     string `json:"deleted" form:"deleted" binding:"omitempty,oneof=true only" validateMsg:"oneof=deleted 只能是 true 或 only"`
+	Sort        string `json:"sort" form:"sort" binding:"omitempty,oneof=id -id created_at -created_at email -email username -username" validateMsg:"oneof=sort 只能是 id、created_at、email、username（加上 - 為由大到小）"`
+}
+
+// AdminUserDTO 管理者列表中的使用者資料
+type AdminUserDTO struct {
+	ID            uint      `json:"id"`
+	Email         string    `json:"email"`
+	Username      string    `json:"username"`
+	Role          string    `json:"role"`
+	IsActive      bool      `json:"is_active"`
+	EmailVerified bool      `json:"email_verified"`
+	TOTPEnabled   bool      `json:"totp_enabled"`
+	CreatedAt     time.Time `json:"created_at"`
+	// DeletedAt 已刪除帳號的刪除時間，未刪除時為 null
+	DeletedAt *time.Time `json:"deleted_at"`
 }
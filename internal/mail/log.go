@@ -0,0 +1,54 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+/**
+ * @File: log.go
+ * @Description:
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/18 下午3:20
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// LogMailer 不真的寄信，只把信件內容寫進檔案或 log，本機開發與測試使用
+type LogMailer struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewLogMailer 建立 LogMailer，path 為空時直接輸出到 log
+func NewLogMailer(path string) *LogMailer {
+	return &LogMailer{path: path}
+}
+
+// Send 記錄信件內容
+func (m *LogMailer) Send(_ context.Context, msg Message) error {
+	content := fmt.Sprintf("==== %s ====\nTo: %s\nSubject: %s\n\n%s\n\n",
+		time.Now().Format(time.RFC3339), msg.To, msg.Subject, msg.Body)
+
+	if m.path == "" {
+		log.Printf("📧 (log mailer)\n%s", content)
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, err := os.OpenFile(m.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	_, err = f.WriteString(content)
+	return err
+}
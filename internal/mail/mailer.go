@@ -0,0 +1,57 @@
+package mail
+
+import (
+	"context"
+	"log"
+	"micro-golang/internal/config"
+)
+
+/**
+ * @File: mailer.go
+ * @Description:
+ *
+ * 寄信抽象層，正式環境使用 SMTP，本機開發與測試可改用寫檔 / log
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/18 下午3:05
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// Message 一封純文字信件
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer 寄信介面
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewFromEnv 依環境變數建立 Mailer
+//
+// MAIL_DRIVER    smtp / log（預設 log）
+// MAIL_FROM      寄件者
+// SMTP_HOST、SMTP_PORT、SMTP_USER、SMTP_PASS  SMTP 設定
+// MAIL_LOG_FILE  log 模式下寫入的檔案，未設定則直接輸出到 log
+func NewFromEnv() Mailer {
+	from := config.GetEnv("MAIL_FROM", "no-reply@microgo.local")
+
+	switch driver := config.GetEnv("MAIL_DRIVER", "log"); driver {
+	case "smtp":
+		return NewSMTPMailer(
+			config.GetEnv("SMTP_HOST", "localhost"),
+			config.GetEnv("SMTP_PORT", "587"),
+			config.GetEnv("SMTP_USER", ""),
+			config.GetEnv("SMTP_PASS", ""),
+			from,
+		)
+	case "log":
+		return NewLogMailer(config.GetEnv("MAIL_LOG_FILE", ""))
+	default:
+		log.Printf("⚠️ 未知的 MAIL_DRIVER=%q，改用 log 模式", driver)
+		return NewLogMailer(config.GetEnv("MAIL_LOG_FILE", ""))
+	}
+}
@@ -0,0 +1,72 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+	"micro-golang/internal/apikey"
+	"micro-golang/internal/config"
+	"micro-golang/internal/rbac"
+	"micro-golang/internal/utils"
+	"net/http"
+)
+
+/**
+ * @File: apikey_middleware.go
+ * @Description:
+ *
+ * JWTAuth 遇到 Authorization: ApiKey <key> 時改用 API 金鑰驗證，
+ * 放入 context 的 email / userId / role 與 JWT 相同，另外放入金鑰的 scopes 供 RequirePermission 檢查。
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/19 下午6:30
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// 目前請求的驗證方式（context key: authMethod）
+const (
+	AuthMethodJWT    = "jwt"
+	AuthMethodAPIKey = "api_key"
+)
+
+// apiKeyAuth 驗證 API 金鑰並設定 context
+func apiKeyAuth(c *gin.Context, key string) {
+	principal, err := apikey.Authenticate(config.Ctx, key)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, utils.JsonResult{
+			StatusCode: "401",
+			Msg:        "Invalid API key",
+			MsgDetail:  "API 金鑰無效、已過期或已撤銷",
+		})
+		c.Abort()
+		return
+	}
+
+	c.Set("email", principal.Email)
+	c.Set("userId", principal.UserID)
+	c.Set("role", principal.Role)
+	c.Set("scopes", principal.Scopes)
+	c.Set("apiKeyId", principal.KeyID)
+	c.Set("authMethod", AuthMethodAPIKey)
+	c.Next()
+}
+
+// DenyAPIKey 拒絕以 API 金鑰呼叫（例如管理金鑰本身的 API，避免金鑰自行產生新金鑰）
+func DenyAPIKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("authMethod") == AuthMethodAPIKey {
+			abortForbidden(c, "此操作不可使用 API 金鑰，請以帳號登入")
+			return
+		}
+		c.Next()
+	}
+}
+
+// scopesFromContext 取出 API 金鑰的 scopes；以 JWT 登入時沒有 scope 限制
+func scopesFromContext(c *gin.Context) ([]rbac.Permission, bool) {
+	val, ok := c.Get("scopes")
+	if !ok {
+		return nil, false
+	}
+	scopes, ok := val.([]rbac.Permission)
+	return scopes, ok
+}
@@ -1,9 +1,11 @@
 package middlewares
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 	"micro-golang/internal/config"
+	"micro-golang/internal/rbac"
+	"micro-golang/internal/tokens"
 	"micro-golang/internal/utils"
 	"net/http"
 	"strings"
@@ -19,71 +21,114 @@ import (
  * @Version:  1.0
  */
 
+// MFASetupClaim 必須啟用兩步驟驗證卻尚未設定的帳號（管理者角色），登入後拿到的 token 帶有此 claim，
+// 只能用在 MFASetupAuth 保護的 /auth/mfa/totp/* 完成設定
+const MFASetupClaim = "mfa_setup"
+
+// JWTAuth 驗證 access token（或 API 金鑰），尚未完成兩步驟驗證設定的 token 一律拒絕
 func JWTAuth() gin.HandlerFunc {
+	return jwtAuth(false)
+}
+
+// MFASetupAuth 與 JWTAuth 相同，但也接受只能用來設定兩步驟驗證的 token
+func MFASetupAuth() gin.HandlerFunc {
+	return jwtAuth(true)
+}
+
+func jwtAuth(allowMFASetup bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// 1. 從 Header 中讀取 Authorization 欄位
 		authHeader := c.GetHeader("Authorization")
 
-		// 2. 檢查 Header 是否以 "Bearer " 開頭
-		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
-			c.JSON(http.StatusUnauthorized, utils.JsonResult{
-				StatusCode: "401",
-				Msg:        "No token provided in Authorization header",
-				MsgDetail:  "請先登入或確認 Request Header 中的 Authorization 格式是否為 Bearer token",
-			})
-			c.Abort()
+		// 機器客戶端使用 API 金鑰
+		if strings.HasPrefix(authHeader, "ApiKey ") {
+			apiKeyAuth(c, strings.TrimPrefix(authHeader, "ApiKey "))
 			return
 		}
 
-		// 3. 擷取 Bearer Token 的實際內容
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+		// 2. 沒有 Authorization header 時改讀 cookie 登入模式的 access_token cookie
+		var tokenString string
+		cookieAuth := false
+		if authHeader == "" {
+			if v, err := c.Cookie(AccessTokenCookie); err == nil && v != "" {
+				tokenString, cookieAuth = v, true
+			}
+		}
 
-		// 4. Redis 黑名單檢查
-		blacklisted, _ := config.RDB.Exists(config.Ctx, "blacklist:access_token:"+tokenString).Result()
-		if blacklisted == 1 {
-			c.JSON(http.StatusUnauthorized, utils.JsonResult{
-				StatusCode: "401",
-				Msg:        "Token is Logout and inValid",
-				MsgDetail:  "Token 已被登出或無效",
-			})
-			c.Abort()
-			return
+		// 3. 檢查 Header 是否以 "Bearer " 開頭，並擷取 Bearer Token 的實際內容
+		if !cookieAuth {
+			if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
+				c.JSON(http.StatusUnauthorized, utils.JsonResult{
+					StatusCode: "401",
+					Msg:        "No token provided in Authorization header",
+					MsgDetail:  "請先登入或確認 Request Header 中的 Authorization 格式是否為 Bearer token 或 ApiKey <key>",
+				})
+				c.Abort()
+				return
+			}
+			tokenString = strings.TrimPrefix(authHeader, "Bearer ")
 		}
 
-		// 5. 驗證並解析 JWT Token
-		claims := jwt.MapClaims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return utils.JwtKey, nil
-		})
+		// 以 cookie 驗證身分時，狀態變更的請求必須通過 CSRF 檢查
+		if cookieAuth && !isSafeMethod(c.Request.Method) && !validCSRF(c) {
+			abortForbidden(c, csrfInvalidDetail)
+			return
+		}
 
-		// 6. 確認 token 有效，若驗證失敗則中止請求
-		if err != nil || !token.Valid {
+		// 4. 驗證 JWT（authsvc 以本地金鑰驗章，其他服務透過快取的 JWKS 驗章），
+		// 並檢查 Redis 黑名單、token 類型與 session 是否已撤銷
+		claims, err := tokens.VerifyAccess(config.Ctx, tokenString)
+		if err != nil {
+			var msg, detail string
+			switch {
+			case errors.Is(err, tokens.ErrRevoked):
+				msg, detail = "Token is Logout and inValid", "Token 已被登出或無效"
+			case errors.Is(err, tokens.ErrWrongType):
+				// 🚨 refresh token、OIDC ID token 都不可當成 access token 使用
+				msg, detail = "Invalid token type", "請使用 access token 進行此操作"
+			case errors.Is(err, tokens.ErrSessionRevoked):
+				msg, detail = "Session revoked", "此裝置的登入已被登出，請重新登入"
+			case errors.Is(err, tokens.ErrStale):
+				// 變更密碼之前發出的 token（以 iat 判斷）
+				msg, detail = "Password changed", "密碼已變更，請重新登入"
+			default:
+				msg, detail = "Invalid token", "Token 無效或已過期，請重新登入"
+			}
 			c.JSON(http.StatusUnauthorized, utils.JsonResult{
 				StatusCode: "401",
-				Msg:        "Invalid token",
-				MsgDetail:  "Token 無效或已過期，請重新登入",
+				Msg:        msg,
+				MsgDetail:  detail,
 			})
 			c.Abort()
 			return
 		}
 
-		// 🚨 token_type 檢查：若為 refresh，拒絕使用
-		if tokenType, ok := claims["token_type"]; ok && tokenType == "refresh" {
-			c.JSON(http.StatusUnauthorized, utils.JsonResult{
-				StatusCode: "401",
-				Msg:        "Invalid token type",
-				MsgDetail:  "請使用 access token 進行此操作",
-			})
-			c.Abort()
+		// 管理者角色在完成兩步驟驗證設定前，token 只能用來設定 TOTP
+		if setup, _ := claims[MFASetupClaim].(bool); setup && !allowMFASetup {
+			abortForbidden(c, "此帳號必須先啟用兩步驟驗證，請呼叫 /auth/mfa/totp/setup 完成設定後重新登入")
 			return
 		}
 
-		// 7. 從 claims 中取出使用者資訊，設定到 Context 讓後續 handlers 使用
+		// 5. 從 claims 中取出使用者資訊，設定到 Context 讓後續 handlers 使用
 		c.Set("email", claims["email"])
 		c.Set("userId", claims["userId"])
 		c.Set("role", claims["role"])
+		c.Set("sessionId", claims["fid"])
+		c.Set("authMethod", AuthMethodJWT)
+		// OAuth 發出的 token 帶有 scope，權限只限於授權的範圍
+		if scope, ok := claims["scope"].(string); ok {
+			c.Set("scopes", rbac.ParseScope(scope))
+			c.Set("clientId", claims["cid"])
+		}
+
+		// 6. 代理登入：另外記錄實際操作的管理者，並在請求結束後寫入稽核紀錄
+		if setImpersonation(c, claims) {
+			c.Next()
+			recordImpersonatedRequest(c)
+			return
+		}
 
-		// 8. 放行
+		// 7. 放行
 		c.Next()
 	}
 }
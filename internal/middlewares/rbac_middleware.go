@@ -0,0 +1,78 @@
+package middlewares
+
+import (
+	"github.com/gin-gonic/gin"
+	"micro-golang/internal/rbac"
+	"micro-golang/internal/utils"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+/**
+ * @File: rbac_middleware.go
+ * @Description:
+ *
+ * 角色 / 權限檢查，需放在 JWTAuth 之後（依賴 context 中的 role）
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/19 下午2:20
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// RequireRole 只允許指定角色存取
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+		for _, r := range roles {
+			if r == role {
+				c.Next()
+				return
+			}
+		}
+		abortForbidden(c, "此操作僅限角色："+strings.Join(roles, "、"))
+	}
+}
+
+// RequirePermission 需同時具備所有指定權限才可存取
+// 以 API 金鑰呼叫時，除了角色權限外，金鑰的 scopes 也必須包含該權限
+func RequirePermission(perms ...rbac.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+		scopes, scoped := scopesFromContext(c)
+		for _, p := range perms {
+			if !rbac.HasPermission(role, p) {
+				abortForbidden(c, "缺少權限："+string(p))
+				return
+			}
+			if scoped && !slices.Contains(scopes, p) {
+				abortForbidden(c, "API 金鑰未授權此 scope："+string(p))
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// RequireSelfOrPermission 路徑參數 param 為自己的使用者 ID 時直接放行，查詢其他人則需具備 perm
+func RequireSelfOrPermission(param string, perm rbac.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if userID, ok := utils.GetUserID(c); ok && c.Param(param) == strconv.FormatUint(uint64(userID), 10) {
+			c.Next()
+			return
+		}
+		RequirePermission(perm)(c)
+	}
+}
+
+// abortForbidden 統一的 403 回應格式
+func abortForbidden(c *gin.Context, detail string) {
+	c.JSON(http.StatusForbidden, utils.JsonResult{
+		StatusCode: utils.CodeForbidden.StatusCode,
+		Msg:        utils.CodeForbidden.Message,
+		MsgDetail:  detail,
+	})
+	c.Abort()
+}
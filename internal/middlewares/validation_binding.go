@@ -2,6 +2,9 @@ package middlewares
 
 import (
 	"github.com/go-playground/validator/v10"
+	"micro-golang/internal/password"
+	"micro-golang/internal/utils"
+	"reflect"
 	"regexp"
 )
 
@@ -17,22 +20,39 @@ import (
  * @Version:  1.0
  */
 
-// UserPwd 密碼驗證
-// 必須要有一個大寫英文一個小寫英文及數字，至少6碼最多30碼
-// Go 不支援 Lookahead 語法(?= 之類的 所以 ^(?=.*[a-z])(?=.*[A-Z])(?=.*\d)[a-zA-Z\d]{6,30}$ 無法
-func UserPwd(field validator.FieldLevel) bool {
-	password := field.Field().String()
-
-	if len(password) < 6 || len(password) > 30 {
-		return false
+// RegisterPasswordValidations 註冊密碼規則驗證器，每條規則各自一個 tag（password.RuleLength 等），
+// 錯誤訊息依目前設定產生，由 utils.ExtractFieldErrorMessages 回傳不符合的那條規則
+// pwd_personal 會比對同一個 struct 的 Email / Username 欄位
+func RegisterPasswordValidations(v *validator.Validate) {
+	policy := password.CurrentPolicy()
+	for _, rule := range []string{password.RuleLength, password.RuleCharset, password.RuleClasses, password.RulePersonal, password.RuleBreached} {
+		_ = v.RegisterValidation(rule, func(field validator.FieldLevel) bool {
+			return policy.Passes(rule, field.Field().String(), siblingString(field, "Email"), siblingString(field, "Username"))
+		})
+		utils.RegisterValidationMessage(rule, policy.Message(rule))
 	}
+}
 
-	hasUpper := regexp.MustCompile(`[A-Z]`).MatchString(password)
-	hasLower := regexp.MustCompile(`[a-z]`).MatchString(password)
-	hasDigit := regexp.MustCompile(`[0-9]`).MatchString(password)
-	isOnlyAlphaNum := regexp.MustCompile(`^[a-zA-Z0-9]+$`).MatchString(password)
-
-	return hasUpper && hasLower && hasDigit && isOnlyAlphaNum
+// siblingString 取得同一個 struct 中其他字串欄位的值，欄位不存在時回傳空字串
+func siblingString(field validator.FieldLevel, name string) string {
+	parent := field.Parent()
+	if parent.Kind() == reflect.Ptr {
+		parent = parent.Elem()
+	}
+	if parent.Kind() != reflect.Struct {
+		return ""
+	}
+	f := parent.FieldByName(name)
+	if f.Kind() == reflect.Ptr {
+		if f.IsNil() {
+			return ""
+		}
+		f = f.Elem()
+	}
+	if f.Kind() != reflect.String {
+		return ""
+	}
+	return f.String()
 }
 
 // UserName 用戶名稱驗證
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+)
+
+/**
+ * @File: api_key.go
+ * @Description:
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/19 下午6:00
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// APIKey 個人 API 金鑰，供批次程式等機器客戶端使用
+// 只保存完整金鑰的雜湊值；Prefix 為金鑰開頭的公開部分，方便使用者辨識是哪一把
+type APIKey struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"index" json:"user_id"`
+	Name       string     `gorm:"size:64" json:"name"`
+	Prefix     string     `gorm:"size:16;uniqueIndex" json:"prefix"`
+	KeyHash    string     `gorm:"size:64;uniqueIndex" json:"-"`
+	Scopes     string     `gorm:"size:512" json:"scopes"` // 以逗號分隔的權限名稱
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// TableName 對應表名
+func (APIKey) TableName() string {
+	return "api_keys"
+}
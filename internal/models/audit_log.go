@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+)
+
+/**
+ * @File: audit_log.go
+ * @Description:
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/19 下午3:10
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// AuditLog 稽核紀錄，記錄誰在什麼時候對什麼資源做了什麼事
+type AuditLog struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	ActorID    uint      `gorm:"index" json:"actor_id"` // 執行操作的使用者，0 表示系統
+	ActorRole  string    `gorm:"size:32" json:"actor_role"`
+	Action     string    `gorm:"size:64;index" json:"action"`
+	TargetType string    `gorm:"size:32" json:"target_type"`
+	TargetID   string    `gorm:"size:64;index" json:"target_id"`
+	Detail     string    `gorm:"type:text" json:"detail"` // JSON 格式的補充資訊
+	IP         string    `gorm:"size:64" json:"ip"`
+	CreatedAt  time.Time `gorm:"index" json:"created_at"`
+}
+
+// TableName 對應表名
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
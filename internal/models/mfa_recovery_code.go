@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+)
+
+/**
+ * @File: mfa_recovery_code.go
+ * @Description:
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/19 上午9:40
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// MFARecoveryCode 兩步驟驗證的復原碼（只存雜湊值，每組只能使用一次）
+type MFARecoveryCode struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"index" json:"user_id"`
+	CodeHash  string     `gorm:"size:64" json:"-"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TableName 對應表名
+func (MFARecoveryCode) TableName() string {
+	return "mfa_recovery_codes"
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+/**
+ * @File: migrate.go
+ * @Description:
+ *
+ * 資料表結構更新，由 authsvc 啟動時執行
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/18 下午4:30
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// AutoMigrate 建立 / 更新所有資料表
+func AutoMigrate(db *gorm.DB) error {
+	// email_verified_at 是後來才加的欄位，既有帳號視為已驗證，避免上線後舊帳號全部無法登入
+	backfillVerified := !db.Migrator().HasColumn(&User{}, "EmailVerifiedAt")
+
+	if err := db.AutoMigrate(&User{}, &MFARecoveryCode{}, &AuditLog{}, &APIKey{}, &OAuthClient{}, &UserIdentity{}, &PasswordHistory{}, &Order{}); err != nil {
+		return err
+	}
+
+	if backfillVerified {
+		return db.Model(&User{}).
+			Where("email_verified_at IS NULL").
+			Update("email_verified_at", gorm.Expr("created_at")).Error
+	}
+	return nil
+}
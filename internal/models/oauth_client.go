@@ -0,0 +1,59 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+/**
+ * @File: oauth_client.go
+ * @Description:
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/20 上午9:30
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// OAuthClient 已註冊的 OAuth client
+// Public 為 SPA / 行動 App 等無法保存密鑰的 client，只能使用 authorization_code + PKCE；
+// FirstParty 為自家的資源伺服器 / 前端後端，可撤銷一般登入（不是發給任何 client）的 token；
+// 多值欄位（RedirectURIs、Scopes、GrantTypes）依 OAuth 慣例以空白分隔
+type OAuthClient struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	ClientID     string    `gorm:"size:64;uniqueIndex" json:"client_id"`
+	SecretHash   string    `gorm:"size:64" json:"-"`
+	Name         string    `gorm:"size:128" json:"name"`
+	RedirectURIs string    `gorm:"size:1024" json:"redirect_uris"`
+	Scopes       string    `gorm:"size:512" json:"scopes"`
+	GrantTypes   string    `gorm:"size:128" json:"grant_types"`
+	Public       bool      `json:"public"`
+	FirstParty   bool      `json:"first_party"`
+	ServiceRole  string    `gorm:"size:32" json:"service_role"` // client_credentials 取得的 token 所代表的角色
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// TableName 對應表名
+func (OAuthClient) TableName() string {
+	return "oauth_clients"
+}
+
+// AllowsRedirect redirect_uri 是否已註冊（需完全相同）
+func (c OAuthClient) AllowsRedirect(uri string) bool {
+	return containsField(c.RedirectURIs, uri)
+}
+
+// AllowsGrant 是否允許使用指定的 grant type
+func (c OAuthClient) AllowsGrant(grantType string) bool {
+	return containsField(c.GrantTypes, grantType)
+}
+
+func containsField(list string, v string) bool {
+	for _, f := range strings.Fields(list) {
+		if f == v {
+			return true
+		}
+	}
+	return false
+}
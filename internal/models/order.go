@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+)
+
+/**
+ * @File: order.go
+ * @Description:
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/24 上午10:00
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// Order 訂單，屬於財務紀錄：使用者要求刪除個資時只清除收件資訊，金額、品項與 user_id 保留
+type Order struct {
+	ID       uint    `gorm:"primaryKey" json:"id"`
+	UserID   uint    `gorm:"index" json:"user_id"`
+	Item     string  `json:"item"`
+	Amount   float64 `gorm:"type:decimal(12,2)" json:"amount"`
+	Currency string  `gorm:"size:3;default:TWD" json:"currency"`
+	// 收件資訊（個資）
+	RecipientName   string     `json:"recipient_name"`
+	RecipientPhone  string     `gorm:"size:32" json:"recipient_phone"`
+	ShippingAddress string     `json:"shipping_address"`
+	ContactEmail    string     `json:"contact_email"`
+	AnonymizedAt    *time.Time `json:"anonymized_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// TableName 對應表名
+func (Order) TableName() string {
+	return "orders"
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+)
+
+/**
+ * @File: password_history.go
+ * @Description:
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/22 上午11:00
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// PasswordHistory 使用者設定過的密碼雜湊，用來禁止沿用最近使用過的密碼
+type PasswordHistory struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"index" json:"user_id"`
+	Hash      string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName 對應表名
+func (PasswordHistory) TableName() string {
+	return "password_histories"
+}
@@ -11,19 +11,31 @@ package models
  */
 
 import (
+	"gorm.io/gorm"
 	"time"
 )
 
 // User 建立User Table
 type User struct {
-	ID        uint      `gorm:"primary" json:"id"`
-	Email     string    `gorm:"unique" json:"email"`
-	Password  string    `json:"password"`
-	Username  string    `json:"username"`
-	Role      string    `json:"role"`
-	IsActive  bool      `gorm:"default:true" json:"is_active"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID       uint   `gorm:"primary" json:"id"`
+	Email    string `gorm:"unique" json:"email"`
+	Password string `json:"password"`
+	Username string `json:"username"`
+	Role     string `json:"role"`
+	IsActive bool   `gorm:"default:true" json:"is_active"`
+	// EmailVerifiedAt 完成 Email 驗證的時間，nil 表示尚未驗證，不可登入
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	// PasswordChangedAt 最後一次變更 / 重設密碼的時間，之前發出的 token 一律失效
+	PasswordChangedAt *time.Time `json:"password_changed_at"`
+	// TOTPSecret 加密後的 TOTP 金鑰，設定中（尚未確認）時 TOTPEnabled 仍為 false
+	TOTPSecret  string    `json:"-"`
+	TOTPEnabled bool      `json:"totp_enabled"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	// DeletedAt 軟刪除時間，寬限期內可由管理者復原，之後由背景排程匿名化
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	// AnonymizedAt 個資已清除的時間，之後不可再復原
+	AnonymizedAt *time.Time `json:"-"`
 }
 
 // TableName 對應表名，若不加預設對應users
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+)
+
+/**
+ * @File: user_identity.go
+ * @Description:
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/21 上午10:00
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// UserIdentity 使用者在外部身分提供者（Google、GitLab、Keycloak 等）的帳號
+// Provider + Subject（IdP 的 sub claim）唯一對應一個本地使用者，一個使用者可連結多個外部帳號
+type UserIdentity struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	UserID      uint       `gorm:"index" json:"user_id"`
+	Provider    string     `gorm:"size:64;uniqueIndex:idx_identity_provider_subject" json:"provider"`
+	Subject     string     `gorm:"size:255;uniqueIndex:idx_identity_provider_subject" json:"subject"`
+	Email       string     `json:"email"` // 最近一次登入時 IdP 提供的 Email，僅供參考
+	LastLoginAt *time.Time `json:"last_login_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// TableName 對應表名
+func (UserIdentity) TableName() string {
+	return "user_identities"
+}
@@ -1,7 +1,10 @@
 package order
 
 import (
+	"errors"
 	"github.com/gin-gonic/gin"
+	"micro-golang/internal/middlewares"
+	"micro-golang/internal/utils"
 	"micro-golang/pkg/client"
 	"net/http"
 )
@@ -35,13 +38,17 @@ func (h *Handler) GetOrder(c *gin.Context) {
 		"amount": 99.9,
 	}
 
-	// 從 Order Service 的請求 Header 中獲取 Authorization Token
-	token := c.GetHeader("Authorization")
+	// 訂單屬於目前登入的使用者
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		utils.ReturnError(c, utils.CodeUnauthorized, nil, "無法取得使用者 ID")
+		return
+	}
 
 	// 互打 User Service，傳遞 Token
-	user, err := h.uc.FetchUser("123", token)
+	user, err := h.uc.FetchUser(userID, forwardToken(c))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		userServiceError(c, err)
 		return
 	}
 
@@ -53,7 +60,7 @@ func (h *Handler) GetOrder(c *gin.Context) {
 
 func (h *Handler) GetOrderWithEmail(c *gin.Context) {
 	orderID := c.Param("id")
-	token := c.GetHeader("Authorization") // 假設從 Order Service 的請求 Header 中獲取 Token
+	token := forwardToken(c) // 從 Order Service 的請求 Header（或 cookie）中獲取 Token
 
 	order := map[string]interface{}{
 		"id":     orderID,
@@ -61,9 +68,15 @@ func (h *Handler) GetOrderWithEmail(c *gin.Context) {
 		"amount": 199.9,
 	}
 
-	userEmail, err := h.uc.FetchUserEmail("456", token) // 呼叫新的方法
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		utils.ReturnError(c, utils.CodeUnauthorized, nil, "無法取得使用者 ID")
+		return
+	}
+
+	userEmail, err := h.uc.FetchUserEmail(userID, token) // 呼叫新的方法
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user email: " + err.Error()})
+		userServiceError(c, err)
 		return
 	}
 
@@ -72,3 +85,27 @@ func (h *Handler) GetOrderWithEmail(c *gin.Context) {
 		"userEmail": userEmail,
 	})
 }
+
+// forwardToken 轉送給 usersvc 的 Authorization；Cookie 登入模式改以 cookie 中的 access token 轉送
+func forwardToken(c *gin.Context) string {
+	if token := c.GetHeader("Authorization"); token != "" {
+		return token
+	}
+	if token, err := c.Cookie(middlewares.AccessTokenCookie); err == nil {
+		return "Bearer " + token
+	}
+	return ""
+}
+
+// userServiceError 把 usersvc 的錯誤轉成統一回應
+func userServiceError(c *gin.Context, err error) {
+	var apiErr *client.APIError
+	switch {
+	case errors.Is(err, client.ErrUserNotFound):
+		utils.ReturnError(c, utils.CodeNotFound, nil, "使用者不存在")
+	case errors.As(err, &apiErr):
+		utils.ReturnError(c, utils.CodeServerError, nil, "User Service 回應錯誤："+apiErr.MsgDetail)
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user: " + err.Error()})
+	}
+}
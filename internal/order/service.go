@@ -1,15 +1,40 @@
 package order
 
+import (
+	"context"
+	"gorm.io/gorm"
+	"micro-golang/internal/models"
+	"time"
+)
+
 /**
  * @File: service.go
  * @Description:
  *
+ * 訂單資料存取，個資匯出 / 刪除時由 usersvc 呼叫（各服務共用同一個資料庫）
+ *
  * @Author: Timmy
  * @Create: 2025/4/23 上午11:11
  * @Software: GoLand
  * @Version:  1.0
  */
 
-func main() {
+// ListByUser 列出使用者所有訂單（個資匯出）
+func ListByUser(ctx context.Context, db *gorm.DB, userID uint) ([]models.Order, error) {
+	orders := []models.Order{}
+	err := db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at").Find(&orders).Error
+	return orders, err
+}
 
+// AnonymizeByUser 清除使用者訂單中的收件個資；訂單屬於財務紀錄，金額、品項與 user_id 保留
+func AnonymizeByUser(tx *gorm.DB, userID uint) error {
+	return tx.Model(&models.Order{}).
+		Where("user_id = ? AND anonymized_at IS NULL", userID).
+		Updates(map[string]interface{}{
+			"recipient_name":   "",
+			"recipient_phone":  "",
+			"shipping_address": "",
+			"contact_email":    "",
+			"anonymized_at":    time.Now(),
+		}).Error
 }
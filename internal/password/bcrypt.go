@@ -0,0 +1,70 @@
+package password
+
+import (
+	"errors"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+)
+
+/**
+ * @File: bcrypt.go
+ * @Description:
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/21 下午4:10
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+const DefaultBcryptCost = bcrypt.DefaultCost
+
+// BcryptHasher bcrypt 雜湊，成本記錄在雜湊值中
+type BcryptHasher struct {
+	cost int
+}
+
+// NewBcryptHasher 建立 bcrypt 雜湊，成本超出範圍時使用預設值
+func NewBcryptHasher(cost int) *BcryptHasher {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		cost = DefaultBcryptCost
+	}
+	return &BcryptHasher{cost: cost}
+}
+
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+func (h *BcryptHasher) Verify(password string, encoded string) (bool, error) {
+	if !isBcrypt(encoded) {
+		return false, ErrUnknownFormat
+	}
+	return bcryptVerify(password, encoded)
+}
+
+func (h *BcryptHasher) NeedsRehash(encoded string) bool {
+	if !isBcrypt(encoded) {
+		return true
+	}
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost != h.cost
+}
+
+func isBcrypt(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
+
+func bcryptVerify(password string, encoded string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,103 @@
+package password
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+/**
+ * @File: breached.go
+ * @Description:
+ *
+ * 離線外洩密碼檢查
+ * 清單檔案每行一個密碼的 SHA-1（十六進位，不分大小寫），可直接使用 Have I Been Pwned 的
+ * "雜湊:次數" 格式；空行與 # 開頭的行會略過。檔案第一次使用時載入並排序，之後以二分搜尋比對。
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/22 上午10:30
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+type breachedList struct {
+	once   sync.Once
+	hashes [][sha1.Size]byte
+}
+
+var (
+	breachedMu    sync.Mutex
+	breachedLists = map[string]*breachedList{}
+)
+
+// IsBreached 密碼是否出現在外洩清單中；清單無法讀取時視為沒有外洩（只記 log，不阻擋使用者）
+func IsBreached(path string, plain string) bool {
+	list := loadBreachedList(path)
+	sum := sha1.Sum([]byte(plain))
+	i := sort.Search(len(list.hashes), func(i int) bool {
+		return bytes.Compare(list.hashes[i][:], sum[:]) >= 0
+	})
+	return i < len(list.hashes) && list.hashes[i] == sum
+}
+
+func loadBreachedList(path string) *breachedList {
+	breachedMu.Lock()
+	list, ok := breachedLists[path]
+	if !ok {
+		list = &breachedList{}
+		breachedLists[path] = list
+	}
+	breachedMu.Unlock()
+
+	list.once.Do(func() {
+		hashes, err := readBreachedFile(path)
+		if err != nil {
+			log.Printf("⚠️ 無法讀取外洩密碼清單 %s：%v", path, err)
+			return
+		}
+		list.hashes = hashes
+		log.Printf("✅ 已載入外洩密碼清單 %s，共 %d 筆", path, len(hashes))
+	})
+	return list
+}
+
+func readBreachedFile(path string) ([][sha1.Size]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	var hashes [][sha1.Size]byte
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line, _, _ = strings.Cut(line, ":")
+		if len(line) != hex.EncodedLen(sha1.Size) {
+			continue
+		}
+		var h [sha1.Size]byte
+		if _, err := hex.Decode(h[:], []byte(line)); err != nil {
+			continue
+		}
+		hashes = append(hashes, h)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(hashes, func(i, j int) bool {
+		return bytes.Compare(hashes[i][:], hashes[j][:]) < 0
+	})
+	return hashes, nil
+}
@@ -0,0 +1,50 @@
+package password
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"micro-golang/internal/blacklist"
+	"micro-golang/internal/models"
+	"micro-golang/internal/session"
+	"time"
+)
+
+/**
+ * @File: change.go
+ * @Description:
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/22 下午2:00
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// Update 設定新密碼（重設與變更密碼共用），呼叫前應已通過 Validate 與 CheckReuse
+// 更新雜湊與 password_changed_at 並記錄歷史；之後讓變更前發出的 token 全部失效並撤銷所有 session
+func Update(ctx context.Context, db *gorm.DB, userID uint, plain string) error {
+	hashed, err := Hash(plain)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	err = db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"password":            hashed,
+			"password_changed_at": now,
+		}).Error; err != nil {
+			return err
+		}
+		return Remember(tx, userID, hashed)
+	})
+	if err != nil {
+		return err
+	}
+
+	// iat 早於變更時間的 token 由 JWTAuth 拒絕；同一秒內發出的 token 則由撤銷 session 處理
+	if err := blacklist.RevokeUserTokensBefore(ctx, userID, now); err != nil {
+		return err
+	}
+	_, err = session.RevokeAll(ctx, userID)
+	return err
+}
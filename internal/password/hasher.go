@@ -0,0 +1,102 @@
+package password
+
+import (
+	"errors"
+	"log"
+	"micro-golang/internal/config"
+	"strings"
+	"sync"
+)
+
+/**
+ * @File: hasher.go
+ * @Description:
+ *
+ * 密碼雜湊抽象層，支援 bcrypt 與 argon2id
+ * 雜湊值本身帶有演算法與參數（bcrypt 的 $2a$<cost>$、argon2id 的 PHC 字串），
+ * 因此更換演算法或調整成本後，舊雜湊仍可驗證，並可在登入成功時重新雜湊。
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/21 下午4:00
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+const (
+	AlgBcrypt   = "bcrypt"
+	AlgArgon2id = "argon2id"
+)
+
+var ErrUnknownFormat = errors.New("unknown password hash format")
+
+// PasswordHasher 密碼雜湊演算法
+type PasswordHasher interface {
+	// Hash 以目前參數雜湊密碼
+	Hash(password string) (string, error)
+	// Verify 比對密碼，密碼錯誤時回傳 false 與 nil
+	Verify(password string, encoded string) (bool, error)
+	// NeedsRehash 雜湊值不是此演算法或參數與目前設定不同時回傳 true
+	NeedsRehash(encoded string) bool
+}
+
+var (
+	preferredOnce sync.Once
+	preferred     PasswordHasher
+)
+
+// NewFromEnv 依環境變數建立偏好的 PasswordHasher
+//
+// PASSWORD_HASH_ALG  argon2id / bcrypt（預設 argon2id）
+// BCRYPT_COST        bcrypt 成本（預設 10）
+// ARGON2_MEMORY      argon2id 記憶體用量，單位 KiB（預設 19456，即 19 MiB）
+// ARGON2_TIME        argon2id 迭代次數（預設 2）
+// ARGON2_THREADS     argon2id 平行度（預設 1）
+func NewFromEnv() PasswordHasher {
+	bcryptHasher := NewBcryptHasher(config.GetEnvInt("BCRYPT_COST", DefaultBcryptCost))
+
+	switch alg := strings.ToLower(config.GetEnv("PASSWORD_HASH_ALG", AlgArgon2id)); alg {
+	case AlgArgon2id:
+		return NewArgon2idHasher(Argon2idParams{
+			Memory:      uint32(config.GetEnvInt("ARGON2_MEMORY", int(DefaultArgon2idParams.Memory))),
+			Iterations:  uint32(config.GetEnvInt("ARGON2_TIME", int(DefaultArgon2idParams.Iterations))),
+			Parallelism: uint8(config.GetEnvInt("ARGON2_THREADS", int(DefaultArgon2idParams.Parallelism))),
+			SaltLength:  DefaultArgon2idParams.SaltLength,
+			KeyLength:   DefaultArgon2idParams.KeyLength,
+		})
+	case AlgBcrypt:
+		return bcryptHasher
+	default:
+		log.Printf("⚠️ 未知的 PASSWORD_HASH_ALG=%q，改用 bcrypt", alg)
+		return bcryptHasher
+	}
+}
+
+// Preferred 目前偏好的 PasswordHasher，第一次呼叫時依環境變數建立
+func Preferred() PasswordHasher {
+	preferredOnce.Do(func() {
+		preferred = NewFromEnv()
+	})
+	return preferred
+}
+
+// Hash 以偏好的演算法雜湊密碼
+func Hash(password string) (string, error) {
+	return Preferred().Hash(password)
+}
+
+// Verify 依雜湊值的格式選擇演算法比對密碼；格式無法辨識（例如外部登入建立、沒有密碼的帳號）時回傳 ErrUnknownFormat
+func Verify(password string, encoded string) (bool, error) {
+	switch {
+	case isBcrypt(encoded):
+		return bcryptVerify(password, encoded)
+	case isArgon2id(encoded):
+		return argon2idVerify(password, encoded)
+	default:
+		return false, ErrUnknownFormat
+	}
+}
+
+// NeedsRehash 雜湊值是否需要以偏好的演算法 / 參數重新雜湊
+func NeedsRehash(encoded string) bool {
+	return Preferred().NeedsRehash(encoded)
+}
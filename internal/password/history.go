@@ -0,0 +1,62 @@
+package password
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"micro-golang/internal/models"
+)
+
+/**
+ * @File: history.go
+ * @Description:
+ *
+ * 密碼歷史：禁止沿用最近 PASSWORD_HISTORY 次用過的密碼
+ * 歷史紀錄保存雜湊值（與 users.password 相同格式），比對時逐筆 Verify。
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/22 上午11:10
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// CheckReuse 新密碼與目前密碼或最近幾次的密碼相同時回傳 *PolicyError
+func CheckReuse(ctx context.Context, db *gorm.DB, userID uint, currentHash string, plain string) error {
+	p := CurrentPolicy()
+	if p.HistorySize <= 0 {
+		return nil
+	}
+
+	var history []models.PasswordHistory
+	if err := db.WithContext(ctx).Where("user_id = ?", userID).
+		Order("id DESC").Limit(p.HistorySize).Find(&history).Error; err != nil {
+		return err
+	}
+	hashes := []string{currentHash}
+	for _, h := range history {
+		hashes = append(hashes, h.Hash)
+	}
+	for _, hash := range hashes {
+		if ok, _ := Verify(plain, hash); ok {
+			return &PolicyError{Violations: []Violation{{Rule: RuleReuse, Message: p.Message(RuleReuse)}}}
+		}
+	}
+	return nil
+}
+
+// Remember 記錄新設定的密碼雜湊，只保留最近 PASSWORD_HISTORY 筆（可傳入交易中的 *gorm.DB）
+func Remember(db *gorm.DB, userID uint, hash string) error {
+	p := CurrentPolicy()
+	if p.HistorySize <= 0 {
+		return nil
+	}
+	if err := db.Create(&models.PasswordHistory{UserID: userID, Hash: hash}).Error; err != nil {
+		return err
+	}
+
+	var keep []uint
+	if err := db.Model(&models.PasswordHistory{}).Where("user_id = ?", userID).
+		Order("id DESC").Limit(p.HistorySize).Pluck("id", &keep).Error; err != nil {
+		return err
+	}
+	return db.Where("user_id = ? AND id NOT IN ?", userID, keep).Delete(&models.PasswordHistory{}).Error
+}
@@ -0,0 +1,264 @@
+package password
+
+import (
+	"fmt"
+	"log"
+	"micro-golang/internal/config"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+/**
+ * @File: policy.go
+ * @Description:
+ *
+ * 密碼規則，可由環境變數調整
+ * 每條規則有獨立的名稱（同時是 validator tag），錯誤時可回報是哪一條規則不符。
+ * 與帳號相關的規則（不可包含 Email / 使用者名稱、不可沿用舊密碼）需要使用者資料，
+ * 重設 / 變更密碼時由 service 呼叫 Validate 與 CheckReuse 檢查。
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/22 上午10:00
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// 規則名稱，同時是 binding tag 的名稱
+const (
+	RuleLength   = "pwd_length"   // 長度
+	RuleCharset  = "pwd_charset"  // 可使用的字元（符號、Unicode）
+	RuleClasses  = "pwd_classes"  // 必須包含的字元種類
+	RulePersonal = "pwd_personal" // 不可包含 Email / 使用者名稱
+	RuleBreached = "pwd_breached" // 不可為已外洩的密碼
+	RuleReuse    = "pwd_reuse"    // 不可沿用最近使用過的密碼
+)
+
+// 字元種類
+const (
+	ClassUpper  = "upper"
+	ClassLower  = "lower"
+	ClassDigit  = "digit"
+	ClassSymbol = "symbol"
+)
+
+// personalMinLength Email 帳號 / 使用者名稱太短（例如 "a"）時不檢查，避免誤判
+const personalMinLength = 3
+
+// bcryptMaxBytes bcrypt 只能處理 72 bytes 以內的密碼，超過時無法雜湊
+const bcryptMaxBytes = 72
+
+// Policy 密碼規則
+type Policy struct {
+	MinLength    int      // 最少字數（以 Unicode 字元計算）
+	MaxLength    int      // 最多字數
+	MaxBytes     int      // 最多位元組數（UTF-8），0 表示不限制；偏好的雜湊為 bcrypt 時為 72
+	Require      []string // 必須包含的字元種類
+	AllowSymbols bool     // 是否可使用符號與空白
+	AllowUnicode bool     // 是否可使用非 ASCII 字元（中文等）
+	NoPersonal   bool     // 不可包含 Email 帳號或使用者名稱
+	HistorySize  int      // 不可與最近幾次的密碼相同，0 表示不檢查
+	BreachedFile string   // 外洩密碼 SHA-1 清單檔案，空字串表示不檢查
+}
+
+// Violation 不符合的規則
+type Violation struct {
+	Rule    string
+	Message string
+}
+
+// PolicyError 密碼不符合規則
+type PolicyError struct {
+	Violations []Violation
+}
+
+func (e *PolicyError) Error() string {
+	return "password policy violated: " + e.Violations[0].Rule
+}
+
+// Messages 轉為與 utils.ExtractFieldErrorMessages 相同格式的欄位錯誤訊息，所有不符合的規則一次列出
+func (e *PolicyError) Messages(field string) map[string]string {
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		messages[i] = v.Message
+	}
+	return map[string]string{field: strings.Join(messages, "；")}
+}
+
+var (
+	policyOnce sync.Once
+	policy     *Policy
+)
+
+// PolicyFromEnv 依環境變數建立密碼規則
+//
+// PASSWORD_MIN_LENGTH、PASSWORD_MAX_LENGTH  長度範圍（預設 8~64），PASSWORD_HASH_ALG 為 bcrypt 時另限制 72 bytes
+// PASSWORD_REQUIRE          必須包含的字元種類，以逗號分隔 upper,lower,digit,symbol（預設 upper,lower,digit）
+// PASSWORD_ALLOW_SYMBOLS    可否使用符號與空白（預設 true）
+// PASSWORD_ALLOW_UNICODE    可否使用非 ASCII 字元（預設 true）
+// PASSWORD_NO_PERSONAL      不可包含 Email 帳號 / 使用者名稱（預設 true）
+// PASSWORD_HISTORY          不可與最近幾次的密碼相同（預設 5，0 為不檢查）
+// PASSWORD_BREACHED_FILE    外洩密碼清單檔案（預設不檢查）
+func PolicyFromEnv() *Policy {
+	p := &Policy{
+		MinLength:    config.GetEnvInt("PASSWORD_MIN_LENGTH", 8),
+		MaxLength:    config.GetEnvInt("PASSWORD_MAX_LENGTH", 64),
+		AllowSymbols: config.GetEnvBool("PASSWORD_ALLOW_SYMBOLS", true),
+		AllowUnicode: config.GetEnvBool("PASSWORD_ALLOW_UNICODE", true),
+		NoPersonal:   config.GetEnvBool("PASSWORD_NO_PERSONAL", true),
+		HistorySize:  config.GetEnvInt("PASSWORD_HISTORY", 5),
+		BreachedFile: config.GetEnv("PASSWORD_BREACHED_FILE", ""),
+	}
+	for _, class := range strings.Split(config.GetEnv("PASSWORD_REQUIRE", "upper,lower,digit"), ",") {
+		switch class = strings.TrimSpace(class); class {
+		case ClassUpper, ClassLower, ClassDigit, ClassSymbol:
+			p.Require = append(p.Require, class)
+		case "":
+		default:
+			log.Printf("⚠️ 未知的 PASSWORD_REQUIRE 字元種類 %q，已略過", class)
+		}
+	}
+	if _, ok := Preferred().(*BcryptHasher); ok {
+		p.MaxBytes = bcryptMaxBytes
+	}
+	if p.MaxLength < p.MinLength {
+		log.Printf("⚠️ PASSWORD_MAX_LENGTH 小於 PASSWORD_MIN_LENGTH，改用 %d", p.MinLength)
+		p.MaxLength = p.MinLength
+	}
+	return p
+}
+
+// CurrentPolicy 目前使用的密碼規則，第一次呼叫時依環境變數建立
+func CurrentPolicy() *Policy {
+	policyOnce.Do(func() {
+		policy = PolicyFromEnv()
+	})
+	return policy
+}
+
+// Validate 以目前規則檢查密碼（不含沿用舊密碼），email / username 用於檢查是否包含個人資料
+func Validate(plain string, email string, username string) error {
+	if violations := CurrentPolicy().Check(plain, email, username); len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// Check 依序檢查各規則，回傳所有不符合的規則
+func (p *Policy) Check(plain string, email string, username string) []Violation {
+	var violations []Violation
+	for _, rule := range []string{RuleLength, RuleCharset, RuleClasses, RulePersonal, RuleBreached} {
+		if !p.Passes(rule, plain, email, username) {
+			violations = append(violations, Violation{Rule: rule, Message: p.Message(rule)})
+		}
+	}
+	return violations
+}
+
+// Passes 檢查單一規則
+func (p *Policy) Passes(rule string, plain string, email string, username string) bool {
+	switch rule {
+	case RuleLength:
+		n := utf8.RuneCountInString(plain)
+		return n >= p.MinLength && n <= p.MaxLength && (p.MaxBytes == 0 || len(plain) <= p.MaxBytes)
+	case RuleCharset:
+		return p.validCharset(plain)
+	case RuleClasses:
+		return p.hasRequiredClasses(plain)
+	case RulePersonal:
+		return !p.NoPersonal || !containsPersonal(plain, email, username)
+	case RuleBreached:
+		return p.BreachedFile == "" || !IsBreached(p.BreachedFile, plain)
+	default:
+		return true
+	}
+}
+
+// Message 規則的錯誤訊息（依目前設定產生）
+func (p *Policy) Message(rule string) string {
+	switch rule {
+	case RuleLength:
+		if p.MaxBytes > 0 {
+			return fmt.Sprintf("密碼長度需為 %d~%d 字，且不可超過 %d bytes（中文等非英文字元每字佔 3 bytes）", p.MinLength, p.MaxLength, p.MaxBytes)
+		}
+		return fmt.Sprintf("密碼長度需為 %d~%d 字", p.MinLength, p.MaxLength)
+	case RuleCharset:
+		switch {
+		case !p.AllowSymbols && !p.AllowUnicode:
+			return "密碼只能包含英文字母與數字"
+		case !p.AllowSymbols:
+			return "密碼不可包含符號或空白"
+		default:
+			return "密碼只能包含英文字母、數字與符號"
+		}
+	case RuleClasses:
+		names := map[string]string{ClassUpper: "大寫字母", ClassLower: "小寫字母", ClassDigit: "數字", ClassSymbol: "符號"}
+		var required []string
+		for _, class := range p.Require {
+			required = append(required, names[class])
+		}
+		return "密碼需至少包含一個" + strings.Join(required, "、")
+	case RulePersonal:
+		return "密碼不可包含 Email 或使用者名稱"
+	case RuleBreached:
+		return "此密碼曾出現在外洩密碼清單中，請改用其他密碼"
+	case RuleReuse:
+		return fmt.Sprintf("不可使用最近 %d 次用過的密碼", p.HistorySize)
+	default:
+		return "密碼不符合規則"
+	}
+}
+
+// validCharset 控制字元一律不可使用；符號（含空白）與非 ASCII 字元依設定
+func (p *Policy) validCharset(plain string) bool {
+	for _, r := range plain {
+		switch {
+		case r == utf8.RuneError || unicode.IsControl(r):
+			return false
+		case r > unicode.MaxASCII && !p.AllowUnicode:
+			return false
+		case isSymbol(r) && !p.AllowSymbols:
+			return false
+		}
+	}
+	return true
+}
+
+func (p *Policy) hasRequiredClasses(plain string) bool {
+	for _, class := range p.Require {
+		var match func(rune) bool
+		switch class {
+		case ClassUpper:
+			match = unicode.IsUpper
+		case ClassLower:
+			match = unicode.IsLower
+		case ClassDigit:
+			match = unicode.IsDigit
+		case ClassSymbol:
+			match = isSymbol
+		}
+		if strings.IndexFunc(plain, match) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// isSymbol 不是字母也不是數字的可見字元（含空白）
+func isSymbol(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsControl(r)
+}
+
+// containsPersonal 密碼是否包含 Email 帳號（@ 之前）或使用者名稱，不分大小寫
+func containsPersonal(plain string, email string, username string) bool {
+	lower := strings.ToLower(plain)
+	local, _, _ := strings.Cut(email, "@")
+	for _, s := range []string{local, username} {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if utf8.RuneCountInString(s) >= personalMinLength && strings.Contains(lower, s) {
+			return true
+		}
+	}
+	return false
+}
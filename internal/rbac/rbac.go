@@ -0,0 +1,127 @@
+package rbac
+
+import (
+	"strings"
+)
+
+/**
+ * @File: rbac.go
+ * @Description:
+ *
+ * 角色與權限對照表
+ * 角色由低到高為 User < Admin < SuperAdmin，高階角色擁有低階角色的所有權限。
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/19 下午2:00
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+const (
+	RoleUser       = "User"
+	RoleAdmin      = "Admin"
+	RoleSuperAdmin = "SuperAdmin"
+)
+
+// Permission 權限名稱，格式為 資源:動作
+type Permission string
+
+const (
+	PermProfileRead  Permission = "profile:read"  // 讀取自己的個人資料
+	PermProfileWrite Permission = "profile:write" // 修改自己的個人資料
+	PermUserRead     Permission = "user:read"     // 查詢使用者（一般使用者只能查自己）
+	PermOrderRead    Permission = "order:read"    // 查詢訂單
+
+	PermUserReadAny Permission = "user:read:any" // 查詢任何使用者
+	PermUserManage  Permission = "user:manage"   // 建立、停用使用者等管理操作
+	// PermUserImpersonate 以其他（較低階）使用者的身分操作，供客服重現問題
+	PermUserImpersonate Permission = "user:impersonate"
+
+	PermOAuthClientManage Permission = "oauth_client:manage" // 註冊 / 刪除 OAuth client
+)
+
+// rolePermissions 各角色「額外」擁有的權限，實際權限會再加上較低階角色的權限
+var rolePermissions = map[string][]Permission{
+	RoleUser: {
+		PermProfileRead,
+		PermProfileWrite,
+		PermUserRead,
+		PermOrderRead,
+	},
+	RoleAdmin: {
+		PermUserReadAny,
+		PermUserManage,
+		PermUserImpersonate,
+	},
+	RoleSuperAdmin: {
+		PermOAuthClientManage,
+	},
+}
+
+// roleRank 角色階級，數字越大權限越高
+var roleRank = map[string]int{
+	RoleUser:       1,
+	RoleAdmin:      2,
+	RoleSuperAdmin: 3,
+}
+
+// IsValidRole 是否為系統定義的角色
+func IsValidRole(role string) bool {
+	_, ok := roleRank[role]
+	return ok
+}
+
+// Permissions 取得角色的完整權限（包含較低階角色的權限）
+func Permissions(role string) []Permission {
+	rank, ok := roleRank[role]
+	if !ok {
+		return nil
+	}
+	var perms []Permission
+	for r, permsOfRole := range rolePermissions {
+		if roleRank[r] <= rank {
+			perms = append(perms, permsOfRole...)
+		}
+	}
+	return perms
+}
+
+// HasPermission 角色是否擁有指定權限
+func HasPermission(role string, perm Permission) bool {
+	for _, p := range Permissions(role) {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// Outranks 角色 a 的階級是否嚴格高於角色 b（同階不算）
+func Outranks(a string, b string) bool {
+	rankA, okA := roleRank[a]
+	rankB, okB := roleRank[b]
+	return okA && okB && rankA > rankB
+}
+
+// IsValidPermission 是否為系統定義的權限
+func IsValidPermission(perm Permission) bool {
+	return HasPermission(RoleSuperAdmin, perm)
+}
+
+// ParseScope 解析 OAuth scope 字串（以空白分隔的權限名稱）
+func ParseScope(scope string) []Permission {
+	var perms []Permission
+	for _, s := range strings.Fields(scope) {
+		perms = append(perms, Permission(s))
+	}
+	return perms
+}
+
+// FormatScope 將權限組成 OAuth scope 字串
+func FormatScope(perms []Permission) string {
+	parts := make([]string, 0, len(perms))
+	for _, p := range perms {
+		parts = append(parts, string(p))
+	}
+	return strings.Join(parts, " ")
+}
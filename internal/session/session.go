@@ -0,0 +1,166 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"micro-golang/internal/blacklist"
+	"micro-golang/internal/config"
+	"micro-golang/internal/utils"
+	"sort"
+	"time"
+)
+
+/**
+ * @File: session.go
+ * @Description:
+ *
+ * 使用者登入 Session 登記表
+ * 以 Redis Hash 儲存，key 為 sessions:<userId>，field 為 session ID，value 為 Session JSON。
+ * Session ID 即 refresh token 的家族 ID（fid），撤銷 session 等同撤銷整個 token 家族。
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/18 上午11:05
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+var ErrNotFound = errors.New("session not found")
+
+// Session 一個裝置（一次登入）的 session 資訊
+type Session struct {
+	ID         string    `json:"id"`
+	UserID     uint      `json:"user_id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+}
+
+// Expired 超過 refresh token 壽命沒有使用，代表 session 已自然失效
+func (s Session) Expired() bool {
+	return time.Since(s.LastUsedAt) > utils.RefreshTokenTTL
+}
+
+func key(userID uint) string {
+	return fmt.Sprintf("sessions:%d", userID)
+}
+
+// Create 登記一個新的 session（登入時呼叫）
+func Create(ctx context.Context, userID uint, id string, userAgent string, ip string) error {
+	now := time.Now()
+	return save(ctx, Session{
+		ID:         id,
+		UserID:     userID,
+		UserAgent:  userAgent,
+		IP:         ip,
+		CreatedAt:  now,
+		LastUsedAt: now,
+	})
+}
+
+// Touch 更新 session 的最後使用時間與 IP（refresh token 時呼叫）
+// 若登記表裡找不到（例如功能上線前就登入的 token），會直接補登記
+func Touch(ctx context.Context, userID uint, id string, userAgent string, ip string) error {
+	if IsRevoked(ctx, id) {
+		return ErrNotFound
+	}
+
+	s, err := Get(ctx, userID, id)
+	if errors.Is(err, ErrNotFound) {
+		return Create(ctx, userID, id, userAgent, ip)
+	}
+	if err != nil {
+		return err
+	}
+	s.IP = ip
+	s.LastUsedAt = time.Now()
+	return save(ctx, *s)
+}
+
+// Get 取得單一 session
+func Get(ctx context.Context, userID uint, id string) (*Session, error) {
+	raw, err := config.RDB.HGet(ctx, key(userID), id).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	var s Session
+	if err := json.Unmarshal([]byte(raw), &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// List 列出使用者所有仍有效的 session，依最後使用時間新到舊排序，順便清掉已過期的
+func List(ctx context.Context, userID uint) ([]Session, error) {
+	all, err := config.RDB.HGetAll(ctx, key(userID)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]Session, 0, len(all))
+	for id, raw := range all {
+		var s Session
+		if err := json.Unmarshal([]byte(raw), &s); err != nil || s.Expired() {
+			config.RDB.HDel(ctx, key(userID), id)
+			continue
+		}
+		sessions = append(sessions, s)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt)
+	})
+	return sessions, nil
+}
+
+// Revoke 撤銷單一 session：從登記表移除並撤銷整個 token 家族
+func Revoke(ctx context.Context, userID uint, id string) error {
+	removed, err := config.RDB.HDel(ctx, key(userID), id).Result()
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return ErrNotFound
+	}
+	return blacklist.RevokeFamily(ctx, id)
+}
+
+// RevokeAll 撤銷使用者所有 session（登出所有裝置），回傳撤銷的數量
+func RevokeAll(ctx context.Context, userID uint) (int, error) {
+	ids, err := config.RDB.HKeys(ctx, key(userID)).Result()
+	if err != nil {
+		return 0, err
+	}
+	for _, id := range ids {
+		if err := blacklist.RevokeFamily(ctx, id); err != nil {
+			return 0, err
+		}
+	}
+	if err := config.RDB.Del(ctx, key(userID)).Err(); err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+// IsRevoked 檢查 session 是否已被撤銷
+func IsRevoked(ctx context.Context, id string) bool {
+	return blacklist.IsFamilyRevoked(ctx, id)
+}
+
+// save 寫回登記表，整個 hash 的 TTL 以最後一次寫入為準
+func save(ctx context.Context, s Session) error {
+	raw, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	pipe := config.RDB.TxPipeline()
+	pipe.HSet(ctx, key(s.UserID), s.ID, raw)
+	pipe.Expire(ctx, key(s.UserID), utils.RefreshTokenTTL)
+	_, err = pipe.Exec(ctx)
+	return err
+}
@@ -0,0 +1,126 @@
+package tokens
+
+import (
+	"context"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"micro-golang/internal/blacklist"
+	"micro-golang/internal/config"
+	"micro-golang/internal/session"
+	"micro-golang/internal/utils"
+)
+
+/**
+ * @File: tokens.go
+ * @Description:
+ *
+ * Token 狀態檢查：簽章、過期、token 類型、Redis 黑名單、變更密碼與 session 撤銷
+ * JWTAuth、/auth/introspect、/auth/revoke 共用，判斷規則只在這裡維護一份。
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/20 下午4:00
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// Token 類型（RFC 7009 token_type_hint 的值）
+const (
+	TypeAccess  = "access_token"
+	TypeRefresh = "refresh_token"
+	TypeID      = "id_token"
+)
+
+// refreshJTIPrefix refresh_token:jti:<jti> → family ID，存在代表這張 refresh token 尚未被使用
+const refreshJTIPrefix = "refresh_token:jti:"
+
+var (
+	ErrInvalid        = errors.New("token invalid or expired")
+	ErrRevoked        = errors.New("token revoked")
+	ErrSessionRevoked = errors.New("session revoked")
+	ErrWrongType      = errors.New("unexpected token type")
+	ErrStale          = errors.New("token issued before password change")
+)
+
+// RefreshJTIKey refresh token jti 登記用的 Redis key
+func RefreshJTIKey(jti string) string {
+	return refreshJTIPrefix + jti
+}
+
+// TypeOf 依 claims 判斷 token 類型（access token 沒有 token_type）
+func TypeOf(claims jwt.MapClaims) string {
+	switch claims["token_type"] {
+	case nil:
+		return TypeAccess
+	case "refresh":
+		return TypeRefresh
+	case "id":
+		return TypeID
+	default:
+		return ""
+	}
+}
+
+// Verify 驗證 token 並檢查是否已被登出 / 撤銷，回傳 claims 與 token 類型
+func Verify(ctx context.Context, token string) (jwt.MapClaims, string, error) {
+	return verify(ctx, token, "")
+}
+
+// VerifyAccess 驗證 access token（JWTAuth 使用）
+func VerifyAccess(ctx context.Context, token string) (jwt.MapClaims, error) {
+	claims, _, err := verify(ctx, token, TypeAccess)
+	return claims, err
+}
+
+// IssuedBeforeRevocation token 是否在使用者變更密碼之前發出（沒有 iat 的舊 token 視為最早發出）
+func IssuedBeforeRevocation(ctx context.Context, claims jwt.MapClaims) bool {
+	userID, ok := utils.ClaimUserID(claims)
+	if !ok {
+		return false
+	}
+	before := blacklist.UserTokensRevokedBefore(ctx, userID)
+	if before == 0 {
+		return false
+	}
+	iat, _ := claims["iat"].(float64)
+	return int64(iat) < before
+}
+
+// verify want 不為空時只接受該類型的 token
+func verify(ctx context.Context, token string, want string) (jwt.MapClaims, string, error) {
+	claims, err := utils.ParseToken(token)
+	if err != nil {
+		return nil, "", ErrInvalid
+	}
+
+	tokenType := TypeOf(claims)
+	if want != "" && tokenType != want {
+		return nil, "", ErrWrongType
+	}
+	familyID, _ := claims["fid"].(string)
+	if tokenType != TypeID && IssuedBeforeRevocation(ctx, claims) {
+		return nil, "", ErrStale
+	}
+	switch tokenType {
+	case TypeAccess:
+		if blacklist.IsAccessTokenBlacklisted(ctx, token) {
+			return nil, "", ErrRevoked
+		}
+		// Session 檢查：該裝置已被登出（或登出所有裝置）時拒絕
+		if familyID != "" && session.IsRevoked(ctx, familyID) {
+			return nil, "", ErrSessionRevoked
+		}
+	case TypeRefresh:
+		if blacklist.IsRefreshTokenBlacklisted(ctx, token) || blacklist.IsFamilyRevoked(ctx, familyID) {
+			return nil, "", ErrRevoked
+		}
+		// 已輪替過的 refresh token 不能再使用
+		jti, _ := claims["jti"].(string)
+		if n, err := config.RDB.Exists(ctx, RefreshJTIKey(jti)).Result(); err != nil || n == 0 {
+			return nil, "", ErrRevoked
+		}
+	case TypeID:
+	default:
+		return nil, "", ErrWrongType
+	}
+	return claims, tokenType, nil
+}
@@ -0,0 +1,302 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"log"
+	"micro-golang/internal/apikey"
+	"micro-golang/internal/audit"
+	"micro-golang/internal/blacklist"
+	"micro-golang/internal/config"
+	"micro-golang/internal/models"
+	"micro-golang/internal/order"
+	"micro-golang/internal/rbac"
+	"micro-golang/internal/session"
+	"strconv"
+	"time"
+)
+
+/**
+ * @File: account.go
+ * @Description:
+ *
+ * 帳號停用 / 啟用、重設兩步驟驗證、刪除（軟刪除）/ 復原，以及寬限期過後的匿名化
+ * 停用或刪除時立即讓已發出的 token、session、API 金鑰快取與個人資料快取失效；
+ * 軟刪除的帳號在 ACCOUNT_DELETION_GRACE 內可由管理者復原，之後由背景排程清除個資（保留 ID 供稽核紀錄對照）。
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/23 下午4:00
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+var (
+	ErrChangeOwnAccount  = errors.New("cannot change own account status")
+	ErrRestoreExpired    = errors.New("account is past the deletion grace period")
+	ErrAccountNotDeleted = errors.New("account is not deleted")
+)
+
+// deletionGrace 軟刪除後可復原的期間
+func deletionGrace() time.Duration {
+	return config.GetEnvDuration("ACCOUNT_DELETION_GRACE", 30*24*time.Hour)
+}
+
+// Deactivate 管理者停用帳號，只能停用比自己低階的使用者
+func (s *Service) Deactivate(ctx context.Context, actor audit.Actor, targetID uint) error {
+	return s.setActive(ctx, actor, targetID, false)
+}
+
+// Reactivate 管理者重新啟用帳號
+func (s *Service) Reactivate(ctx context.Context, actor audit.Actor, targetID uint) error {
+	return s.setActive(ctx, actor, targetID, true)
+}
+
+func (s *Service) setActive(ctx context.Context, actor audit.Actor, targetID uint, active bool) error {
+	if actor.ID == targetID {
+		return ErrChangeOwnAccount
+	}
+
+	var user models.User
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&user, targetID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrUserNotFound
+			}
+			return err
+		}
+		if !rbac.Outranks(actor.Role, user.Role) {
+			return ErrRoleNotAllowed
+		}
+		if user.IsActive == active {
+			return nil
+		}
+
+		// IsActive 有 default:true，需以 Update 才能寫入 false
+		if err := tx.Model(&user).Update("is_active", active).Error; err != nil {
+			return err
+		}
+		action := audit.ActionUserDeactivate
+		if active {
+			action = audit.ActionUserReactivate
+		}
+		return audit.Record(tx, audit.Entry{
+			Actor:      actor,
+			Action:     action,
+			TargetType: "user",
+			TargetID:   strconv.FormatUint(uint64(user.ID), 10),
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	if !active {
+		return s.revokeAccess(ctx, user)
+	}
+	s.invalidateProfile(config.Ctx, user)
+	return nil
+}
+
+// ResetMFA 管理者重設使用者的兩步驟驗證（遺失驗證裝置且復原碼用完時使用）
+// 清除 TOTP 金鑰與復原碼並登出所有裝置；管理者角色重新登入後只能先設定 TOTP
+func (s *Service) ResetMFA(ctx context.Context, actor audit.Actor, targetID uint) error {
+	if actor.ID == targetID {
+		return ErrChangeOwnAccount
+	}
+
+	var user models.User
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&user, targetID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrUserNotFound
+			}
+			return err
+		}
+		if !rbac.Outranks(actor.Role, user.Role) {
+			return ErrRoleNotAllowed
+		}
+
+		if err := tx.Model(&user).Updates(map[string]interface{}{"totp_enabled": false, "totp_secret": ""}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.MFARecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, audit.Entry{
+			Actor:      actor,
+			Action:     audit.ActionMFAReset,
+			TargetType: "user",
+			TargetID:   strconv.FormatUint(uint64(user.ID), 10),
+		})
+	})
+	if err != nil {
+		return err
+	}
+	return s.revokeAccess(ctx, user)
+}
+
+// DeleteAccount 使用者刪除自己的帳號（需再次輸入密碼），寬限期內可請管理者復原
+func (s *Service) DeleteAccount(ctx context.Context, actor audit.Actor, current string) error {
+	user, err := s.verifyCurrentPassword(ctx, actor.ID, current)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(user).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, audit.Entry{
+			Actor:      actor,
+			Action:     audit.ActionUserDelete,
+			TargetType: "user",
+			TargetID:   strconv.FormatUint(uint64(user.ID), 10),
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := apikey.RevokeAll(ctx, user.ID); err != nil {
+		return err
+	}
+	return s.revokeAccess(ctx, *user)
+}
+
+// Restore 管理者在寬限期內復原已刪除的帳號（復原後需重新登入，API 金鑰不會恢復）
+func (s *Service) Restore(ctx context.Context, actor audit.Actor, targetID uint) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Unscoped().First(&user, targetID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrUserNotFound
+			}
+			return err
+		}
+		if !user.DeletedAt.Valid {
+			return ErrAccountNotDeleted
+		}
+		if user.AnonymizedAt != nil || time.Since(user.DeletedAt.Time) > deletionGrace() {
+			return ErrRestoreExpired
+		}
+		if !rbac.Outranks(actor.Role, user.Role) {
+			return ErrRoleNotAllowed
+		}
+
+		if err := tx.Unscoped().Model(&user).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, audit.Entry{
+			Actor:      actor,
+			Action:     audit.ActionUserRestore,
+			TargetType: "user",
+			TargetID:   strconv.FormatUint(uint64(user.ID), 10),
+		})
+	})
+}
+
+// revokeAccess 讓帳號已發出的 token、session 與快取立即失效
+func (s *Service) revokeAccess(ctx context.Context, user models.User) error {
+	s.invalidateProfile(config.Ctx, user)
+	if err := apikey.EvictUser(ctx, user.ID); err != nil {
+		return err
+	}
+	// iat 早於現在的 token 由 JWTAuth 拒絕；同一秒內發出的 token 則由撤銷 session 處理
+	if err := blacklist.RevokeUserTokensBefore(ctx, user.ID, time.Now()); err != nil {
+		return err
+	}
+	_, err := session.RevokeAll(ctx, user.ID)
+	return err
+}
+
+// PurgeDeleted 匿名化超過寬限期的已刪除帳號，回傳成功筆數
+// 單一帳號失敗只記 log 並繼續處理其他帳號，所有失敗合併成一個 error 回傳，下次排程會再重試
+func (s *Service) PurgeDeleted(ctx context.Context) (int, error) {
+	var users []models.User
+	cutoff := time.Now().Add(-deletionGrace())
+	if err := s.db.WithContext(ctx).Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ? AND anonymized_at IS NULL", cutoff).
+		Find(&users).Error; err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	var errs []error
+	for _, user := range users {
+		if err := s.anonymize(ctx, audit.Actor{}, user); err != nil {
+			log.Printf("❌ 匿名化帳號失敗 (user %d)：%v", user.ID, err)
+			errs = append(errs, fmt.Errorf("anonymize user %d: %w", user.ID, err))
+			continue
+		}
+		purged++
+	}
+	return purged, errors.Join(errs...)
+}
+
+// anonymize 清除個資與相關資料（包含訂單收件資訊與稽核紀錄中的個資），
+// Email 改成不會重複的值，讓原本的 Email 可以重新註冊
+// 由背景排程執行時沒有操作者，actor 為零值
+func (s *Service) anonymize(ctx context.Context, actor audit.Actor, user models.User) error {
+	id := strconv.FormatUint(uint64(user.ID), 10)
+	email := user.Email
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&user).Updates(map[string]interface{}{
+			"email":             "deleted-" + id + "@invalid",
+			"username":          "deleted" + id,
+			"password":          "",
+			"is_active":         false,
+			"email_verified_at": nil,
+			"totp_secret":       "",
+			"totp_enabled":      false,
+			"anonymized_at":     time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+		for _, model := range []interface{}{
+			&models.UserIdentity{}, &models.PasswordHistory{}, &models.MFARecoveryCode{}, &models.APIKey{},
+		} {
+			if err := tx.Where("user_id = ?", user.ID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		// 訂單屬於財務紀錄，只清除收件個資；稽核紀錄保留，只清除 IP 與含 Email 的內容
+		if err := order.AnonymizeByUser(tx, user.ID); err != nil {
+			return err
+		}
+		if err := audit.ScrubSubject(tx, user.ID, email); err != nil {
+			return err
+		}
+		return audit.Record(tx, audit.Entry{
+			Actor:      actor,
+			Action:     audit.ActionUserAnonymize,
+			TargetType: "user",
+			TargetID:   id,
+		})
+	})
+}
+
+// StartPurge 啟動背景排程，每 ACCOUNT_PURGE_INTERVAL 匿名化一次超過寬限期的帳號，並清除過期的個資匯出檔
+func (s *Service) StartPurge(ctx context.Context) {
+	interval := config.GetEnvDuration("ACCOUNT_PURGE_INTERVAL", time.Hour)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				n, err := s.PurgeDeleted(ctx)
+				if err != nil {
+					log.Printf("❌ 匿名化已刪除帳號失敗：%v", err)
+				}
+				if n > 0 {
+					log.Printf("🧹 已匿名化 %d 個已刪除帳號", n)
+				}
+				cleanupExports()
+			}
+		}
+	}()
+}
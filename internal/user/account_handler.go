@@ -0,0 +1,110 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"log"
+	"micro-golang/internal/audit"
+	"micro-golang/internal/dto"
+	"micro-golang/internal/utils"
+	"strconv"
+)
+
+/**
+ * @File: account_handler.go
+ * @Description:
+ *
+ * 帳號停用 / 啟用 / 復原 / 重設兩步驟驗證（/admin/users/:id/...）與刪除自己的帳號（DELETE /users/me）
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/23 下午4:30
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// DeactivateUser 管理者停用帳號，已發出的 token 立即失效
+func (h *Handler) DeactivateUser(c *gin.Context) {
+	h.changeAccountStatus(c, h.userService.Deactivate, "User deactivated")
+}
+
+// ReactivateUser 管理者重新啟用帳號
+func (h *Handler) ReactivateUser(c *gin.Context) {
+	h.changeAccountStatus(c, h.userService.Reactivate, "User reactivated")
+}
+
+// RestoreUser 管理者在寬限期內復原已刪除的帳號
+func (h *Handler) RestoreUser(c *gin.Context) {
+	h.changeAccountStatus(c, h.userService.Restore, "User restored")
+}
+
+// ResetUserMFA 管理者重設使用者的兩步驟驗證，使用者所有裝置立即登出
+func (h *Handler) ResetUserMFA(c *gin.Context) {
+	h.changeAccountStatus(c, h.userService.ResetMFA, "MFA reset")
+}
+
+// changeAccountStatus 管理者帳號狀態操作共用的參數解析與錯誤處理
+func (h *Handler) changeAccountStatus(c *gin.Context, op func(ctx context.Context, actor audit.Actor, targetID uint) error, msg string) {
+	targetID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ReturnError(c, utils.CodeParamInvalid, nil, "使用者 ID 格式錯誤")
+		return
+	}
+
+	if err := op(c.Request.Context(), audit.ActorFromContext(c), uint(targetID)); err != nil {
+		switch {
+		case errors.Is(err, ErrUserNotFound):
+			utils.ReturnError(c, utils.CodeNotFound, nil, "找不到使用者")
+		case errors.Is(err, ErrRoleNotAllowed):
+			utils.ReturnError(c, utils.CodeForbidden, nil, "只能操作比自己低階的使用者")
+		case errors.Is(err, ErrChangeOwnAccount):
+			utils.ReturnError(c, utils.CodeForbidden, nil, "不能對自己的帳號執行此操作")
+		case errors.Is(err, ErrAccountNotDeleted):
+			utils.ReturnError(c, utils.CodeParamInvalid, nil, "帳號未被刪除")
+		case errors.Is(err, ErrRestoreExpired):
+			utils.ReturnError(c, utils.CodeForbidden, nil, "已超過復原期限，帳號資料已清除")
+		default:
+			log.Printf("❌ 變更帳號狀態失敗 (user %d)：%v", targetID, err)
+			utils.ReturnError(c, utils.CodeServerError, nil, "變更帳號狀態失敗")
+		}
+		return
+	}
+	utils.ReturnSuccess(c, nil, msg)
+}
+
+// DeleteAccount 刪除自己的帳號（DELETE /users/me），所有裝置立即登出
+func (h *Handler) DeleteAccount(c *gin.Context) {
+	var req dto.AccountDeleteDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		var ve validator.ValidationErrors
+		if errors.As(err, &ve) {
+			utils.ReturnError(c, utils.CodeParamInvalid, utils.ExtractFieldErrorMessages(req, ve), "欄位驗證失敗")
+			return
+		}
+		utils.ReturnError(c, utils.CodeParamInvalid, err.Error())
+		return
+	}
+
+	actor := audit.ActorFromContext(c)
+	if actor.ID == 0 {
+		utils.ReturnError(c, utils.CodeUnauthorized, nil, "無法取得使用者資訊")
+		return
+	}
+
+	if err := h.userService.DeleteAccount(c.Request.Context(), actor, req.CurrentPassword); err != nil {
+		switch {
+		case errors.Is(err, ErrWrongPassword):
+			utils.ReturnError(c, utils.CodeInvalidCredentials, nil, "目前密碼錯誤")
+		case errors.Is(err, ErrTooManyAttempts):
+			utils.ReturnError(c, utils.CodeTooManyRequests, nil, "目前密碼錯誤次數過多，請稍後再試")
+		case errors.Is(err, ErrUserNotFound):
+			utils.ReturnError(c, utils.CodeNotFound, nil, "找不到使用者")
+		default:
+			log.Printf("❌ 刪除帳號失敗 (user %d)：%v", actor.ID, err)
+			utils.ReturnError(c, utils.CodeServerError, nil, "刪除帳號失敗")
+		}
+		return
+	}
+	utils.ReturnSuccess(c, nil, "帳號已刪除")
+}
@@ -0,0 +1,115 @@
+package user
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"micro-golang/internal/audit"
+	"micro-golang/internal/dto"
+	"micro-golang/internal/utils"
+	"strconv"
+)
+
+/**
+ * @File: admin_handler.go
+ * @Description:
+ *
+ * 管理者 API（/admin），需經過 JWTAuth 與權限檢查
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/19 下午4:05
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// CreateUser 管理者建立帳號並指定角色
+func (h *Handler) CreateUser(c *gin.Context) {
+	var req dto.AdminCreateUserDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		var ve validator.ValidationErrors
+		if errors.As(err, &ve) {
+			utils.ReturnError(c, utils.CodeParamInvalid, utils.ExtractFieldErrorMessages(req, ve), "欄位驗證失敗")
+			return
+		}
+		utils.ReturnError(c, utils.CodeParamInvalid, err.Error())
+		return
+	}
+
+	created, err := h.userService.CreateUser(c.Request.Context(), audit.ActorFromContext(c), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrRoleNotAllowed):
+			utils.ReturnError(c, utils.CodeForbidden, nil, "只能建立比自己低階的角色")
+		case errors.Is(err, ErrEmailInUse):
+			utils.ReturnError(c, utils.CodeEmailExists, nil, "該用戶已存在")
+		default:
+			utils.ReturnError(c, utils.CodeServerError, nil, "建立帳號失敗")
+		}
+		return
+	}
+	utils.ReturnSuccess(c, created, "User created")
+}
+
+// ChangeUserRole 管理者調整使用者角色
+func (h *Handler) ChangeUserRole(c *gin.Context) {
+	targetID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.ReturnError(c, utils.CodeParamInvalid, nil, "使用者 ID 格式錯誤")
+		return
+	}
+
+	var req dto.AdminChangeRoleDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		var ve validator.ValidationErrors
+		if errors.As(err, &ve) {
+			utils.ReturnError(c, utils.CodeParamInvalid, utils.ExtractFieldErrorMessages(req, ve), "欄位驗證失敗")
+			return
+		}
+		utils.ReturnError(c, utils.CodeParamInvalid, err.Error())
+		return
+	}
+
+	updated, err := h.userService.ChangeRole(c.Request.Context(), audit.ActorFromContext(c), uint(targetID), req.Role)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrUserNotFound):
+			utils.ReturnError(c, utils.CodeNotFound, nil, "找不到使用者")
+		case errors.Is(err, ErrRoleNotAllowed):
+			utils.ReturnError(c, utils.CodeForbidden, nil, "只能調整比自己低階的使用者，且新角色也必須比自己低階")
+		case errors.Is(err, ErrChangeOwnRole):
+			utils.ReturnError(c, utils.CodeForbidden, nil, "不能調整自己的角色")
+		default:
+			utils.ReturnError(c, utils.CodeServerError, nil, "調整角色失敗")
+		}
+		return
+	}
+	utils.ReturnSuccess(c, updated, "Role updated")
+}
+
+// ListUsers 管理者瀏覽使用者列表（篩選、排序、分頁）
+func (h *Handler) ListUsers(c *gin.Context) {
+	var req dto.AdminUserListQuery
+	if err := c.ShouldBindQuery(&req); err != nil {
+		var ve validator.ValidationErrors
+		if errors.As(err, &ve) {
+			utils.ReturnError(c, utils.CodeParamInvalid, utils.ExtractFieldErrorMessages(req, ve), "欄位驗證失敗")
+			return
+		}
+		utils.ReturnError(c, utils.CodeParamInvalid, err.Error())
+		return
+	}
+
+	page, err := h.userService.ListUsers(c.Request.Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, utils.ErrInvalidCursor):
+			utils.ReturnError(c, utils.CodeParamInvalid, nil, "cursor 無效，或與目前的排序方式不同")
+		case errors.Is(err, ErrInvalidFilter):
+			utils.ReturnError(c, utils.CodeParamInvalid, nil, "篩選條件格式錯誤："+err.Error())
+		default:
+			utils.ReturnError(c, utils.CodeServerError, nil, "查詢使用者失敗")
+		}
+		return
+	}
+	utils.ReturnSuccess(c, page)
+}
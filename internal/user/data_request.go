@@ -0,0 +1,377 @@
+package user
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"log"
+	"micro-golang/internal/apikey"
+	"micro-golang/internal/audit"
+	"micro-golang/internal/config"
+	"micro-golang/internal/dto"
+	"micro-golang/internal/models"
+	"micro-golang/internal/order"
+	"micro-golang/internal/session"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+/**
+ * @File: data_request.go
+ * @Description:
+ *
+ * 個資匯出 / 刪除（GDPR data subject request），在背景執行，狀態存放在 Redis
+ * 匯出：把使用者資料、登入裝置、訂單、稽核紀錄等整理成 JSON 或 ZIP，放在 DATA_EXPORT_DIR 供下載；
+ * 刪除：立即刪除帳號並匿名化個資，訂單等財務紀錄保留，只清除其中的個資。
+ * 多個 usersvc 實例時 DATA_EXPORT_DIR 需為共用的目錄。
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/24 上午11:00
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+const (
+	DataRequestExport = "export"
+	DataRequestErase  = "erase"
+
+	DataRequestPending = "pending"
+	DataRequestRunning = "running"
+	DataRequestDone    = "done"
+	DataRequestFailed  = "failed"
+
+	dataRequestPrefix       = "data_request:"        // data_request:<id> → DataRequestDTO JSON
+	dataRequestActivePrefix = "data_request:active:" // data_request:active:<type>:<userId> → 進行中的工作 ID，避免重複送出
+	dataRequestTimeout      = 10 * time.Minute
+)
+
+var (
+	ErrDataRequestNotFound = errors.New("data request not found")
+	ErrExportNotReady      = errors.New("export is not ready or has expired")
+)
+
+// exportRetention 工作狀態與匯出檔保留的期間
+func exportRetention() time.Duration {
+	return config.GetEnvDuration("DATA_EXPORT_TTL", 7*24*time.Hour)
+}
+
+func exportDir() string {
+	return config.GetEnv("DATA_EXPORT_DIR", "data/exports")
+}
+
+func dataRequestActiveKey(kind string, userID uint) string {
+	return dataRequestActivePrefix + kind + ":" + strconv.FormatUint(uint64(userID), 10)
+}
+
+// RequestExport 建立個資匯出工作；已有進行中的匯出時回傳該工作
+func (s *Service) RequestExport(ctx context.Context, userID uint, format string) (*dto.DataRequestDTO, error) {
+	if format == "" {
+		format = "zip"
+	}
+	return s.startDataRequest(ctx, userID, DataRequestExport, format, func(ctx context.Context, job *dto.DataRequestDTO) error {
+		return s.exportData(ctx, job)
+	})
+}
+
+// RequestErase 驗證密碼後建立個資刪除工作，完成後帳號無法再登入
+func (s *Service) RequestErase(ctx context.Context, actor audit.Actor, current string) (*dto.DataRequestDTO, error) {
+	if _, err := s.verifyCurrentPassword(ctx, actor.ID, current); err != nil {
+		return nil, err
+	}
+	// 稽核紀錄不保留刪除者的 IP
+	actor.IP = ""
+	return s.startDataRequest(ctx, actor.ID, DataRequestErase, "", func(ctx context.Context, job *dto.DataRequestDTO) error {
+		return s.eraseData(ctx, actor, job.UserID)
+	})
+}
+
+// GetDataRequest 取得工作狀態
+func (s *Service) GetDataRequest(ctx context.Context, id string) (*dto.DataRequestDTO, error) {
+	b, err := s.rdb.Get(ctx, dataRequestPrefix+id).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrDataRequestNotFound
+		}
+		return nil, err
+	}
+	var job dto.DataRequestDTO
+	if err := json.Unmarshal(b, &job); err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ExportFile 取得已完成的匯出檔路徑與下載檔名，只有本人可以下載
+func (s *Service) ExportFile(ctx context.Context, userID uint, id string) (string, string, error) {
+	job, err := s.GetDataRequest(ctx, id)
+	if err != nil {
+		return "", "", err
+	}
+	if job.UserID != userID || job.Type != DataRequestExport {
+		return "", "", ErrDataRequestNotFound
+	}
+	if job.Status != DataRequestDone || job.ExpiresAt == nil || time.Now().After(*job.ExpiresAt) {
+		return "", "", ErrExportNotReady
+	}
+	path := exportPath(job)
+	if _, err := os.Stat(path); err != nil {
+		return "", "", ErrExportNotReady
+	}
+	return path, fmt.Sprintf("personal-data-%d-%s.%s", job.UserID, job.CreatedAt.Format("20060102"), job.Format), nil
+}
+
+// exportPath 匯出檔位置
+func exportPath(job *dto.DataRequestDTO) string {
+	return filepath.Join(exportDir(), job.ID+"."+job.Format)
+}
+
+// startDataRequest 登記工作並在背景執行
+func (s *Service) startDataRequest(ctx context.Context, userID uint, kind string, format string, run func(context.Context, *dto.DataRequestDTO) error) (*dto.DataRequestDTO, error) {
+	activeKey := dataRequestActiveKey(kind, userID)
+	if id, err := s.rdb.Get(ctx, activeKey).Result(); err == nil {
+		if job, err := s.GetDataRequest(ctx, id); err == nil {
+			return job, nil
+		}
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, err
+	}
+	job := &dto.DataRequestDTO{
+		ID:        hex.EncodeToString(buf),
+		UserID:    userID,
+		Type:      kind,
+		Format:    format,
+		Status:    DataRequestPending,
+		CreatedAt: time.Now(),
+	}
+	ok, err := s.rdb.SetNX(ctx, activeKey, job.ID, dataRequestTimeout).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		// 同時送出兩次，回傳先建立的工作
+		id, _ := s.rdb.Get(ctx, activeKey).Result()
+		return s.GetDataRequest(ctx, id)
+	}
+	if err := s.saveDataRequest(ctx, job); err != nil {
+		return nil, err
+	}
+
+	go s.runDataRequest(*job, activeKey, run)
+	return job, nil
+}
+
+// runDataRequest 執行工作並更新狀態（不使用請求的 context，請求結束後仍會繼續）
+func (s *Service) runDataRequest(job dto.DataRequestDTO, activeKey string, run func(context.Context, *dto.DataRequestDTO) error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dataRequestTimeout)
+	defer cancel()
+	defer s.rdb.Del(context.Background(), activeKey)
+
+	job.Status = DataRequestRunning
+	_ = s.saveDataRequest(ctx, &job)
+
+	err := run(ctx, &job)
+	now := time.Now()
+	job.FinishedAt = &now
+	job.Status = DataRequestDone
+	if err != nil {
+		log.Printf("❌ 個資%s工作 %s 失敗 (user %d)：%v", job.Type, job.ID, job.UserID, err)
+		job.Status = DataRequestFailed
+		job.Error = "處理失敗，請稍後再試"
+	}
+	if err := s.saveDataRequest(context.Background(), &job); err != nil {
+		log.Printf("❌ 更新個資工作 %s 狀態失敗：%v", job.ID, err)
+	}
+}
+
+func (s *Service) saveDataRequest(ctx context.Context, job *dto.DataRequestDTO) error {
+	b, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return s.rdb.Set(ctx, dataRequestPrefix+job.ID, b, exportRetention()).Err()
+}
+
+// exportSection 匯出檔中的一個類別，ZIP 格式時為 <name>.json
+type exportSection struct {
+	name string
+	data interface{}
+}
+
+// collectPersonalData 整理使用者的所有資料（不含密碼雜湊、兩步驟驗證金鑰等機密）
+func (s *Service) collectPersonalData(ctx context.Context, userID uint) ([]exportSection, error) {
+	var user models.User
+	if err := s.db.WithContext(ctx).First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	profile := map[string]interface{}{
+		"id":                  user.ID,
+		"email":               user.Email,
+		"username":            user.Username,
+		"role":                user.Role,
+		"is_active":           user.IsActive,
+		"email_verified_at":   user.EmailVerifiedAt,
+		"password_changed_at": user.PasswordChangedAt,
+		"totp_enabled":        user.TOTPEnabled,
+		"created_at":          user.CreatedAt,
+		"updated_at":          user.UpdatedAt,
+	}
+
+	sessions, err := session.List(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	orders, err := order.ListByUser(ctx, s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+	identities := []models.UserIdentity{}
+	if err := s.db.WithContext(ctx).Where("user_id = ?", userID).Find(&identities).Error; err != nil {
+		return nil, err
+	}
+	apiKeys, err := apikey.List(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	auditLogs := []models.AuditLog{}
+	if err := s.db.WithContext(ctx).
+		Where("actor_id = ? OR (target_type = ? AND target_id = ?)", userID, "user", strconv.FormatUint(uint64(userID), 10)).
+		Order("created_at").Find(&auditLogs).Error; err != nil {
+		return nil, err
+	}
+	redactAuditLogs(auditLogs, userID)
+
+	return []exportSection{
+		{"profile", profile},
+		{"sessions", sessions},
+		{"orders", orders},
+		{"identities", identities},
+		{"api_keys", apiKeys},
+		{"audit_logs", auditLogs},
+	}, nil
+}
+
+// redactAuditLogs 匯出的稽核紀錄不可帶出第三方的個資
+// 對象不是本人的紀錄（例如管理者操作其他使用者）清除對象 ID 與內容；他人對本人的操作清除操作者的 IP
+func redactAuditLogs(logs []models.AuditLog, userID uint) {
+	self := strconv.FormatUint(uint64(userID), 10)
+	for i := range logs {
+		if logs[i].TargetType != "user" || logs[i].TargetID != self {
+			logs[i].TargetID = ""
+			logs[i].Detail = ""
+		}
+		if logs[i].ActorID != userID {
+			logs[i].IP = ""
+		}
+	}
+}
+
+// exportData 產生匯出檔
+func (s *Service) exportData(ctx context.Context, job *dto.DataRequestDTO) error {
+	sections, err := s.collectPersonalData(ctx, job.UserID)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(exportDir(), 0o700); err != nil {
+		return err
+	}
+
+	path := exportPath(job)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if job.Format == "json" {
+		all := make(map[string]interface{}, len(sections))
+		for _, sec := range sections {
+			all[sec.name] = sec.data
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(all)
+	} else {
+		err = writeExportZip(f, sections)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return err
+	}
+
+	expires := time.Now().Add(exportRetention())
+	job.ExpiresAt = &expires
+	return nil
+}
+
+// writeExportZip 每個類別寫成一個 JSON 檔
+func writeExportZip(f *os.File, sections []exportSection) error {
+	zw := zip.NewWriter(f)
+	for _, sec := range sections {
+		w, err := zw.Create(sec.name + ".json")
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(sec.data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// eraseData 刪除帳號並立即匿名化個資（不等待寬限期，無法復原）
+func (s *Service) eraseData(ctx context.Context, actor audit.Actor, userID uint) error {
+	var user models.User
+	if err := s.db.WithContext(ctx).Unscoped().First(&user, userID).Error; err != nil {
+		return err
+	}
+	if user.AnonymizedAt != nil {
+		return nil
+	}
+	if !user.DeletedAt.Valid {
+		if err := s.db.WithContext(ctx).Delete(&user).Error; err != nil {
+			return err
+		}
+	}
+	if err := apikey.RevokeAll(ctx, user.ID); err != nil {
+		return err
+	}
+	if err := s.revokeAccess(ctx, user); err != nil {
+		return err
+	}
+	return s.anonymize(ctx, actor, user)
+}
+
+// cleanupExports 刪除超過保留期間的匯出檔
+func cleanupExports() {
+	entries, err := os.ReadDir(exportDir())
+	if err != nil {
+		return
+	}
+	cutoff := time.Now().Add(-exportRetention())
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil || e.IsDir() || info.ModTime().After(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(exportDir(), e.Name())); err != nil {
+			log.Printf("⚠️ 刪除過期匯出檔失敗：%v", err)
+		}
+	}
+}
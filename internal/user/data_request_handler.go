@@ -0,0 +1,128 @@
+package user
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"log"
+	"micro-golang/internal/audit"
+	"micro-golang/internal/dto"
+	"micro-golang/internal/utils"
+)
+
+/**
+ * @File: data_request_handler.go
+ * @Description:
+ *
+ * 個資匯出 / 刪除請求（/users/me/export、/users/me/erase）與工作狀態查詢
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/24 上午11:30
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// ExportData 申請匯出自己的個資，完成後以 /users/me/data-requests/:id/download 下載
+func (h *Handler) ExportData(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		utils.ReturnError(c, utils.CodeUnauthorized, nil, "無法取得使用者資訊")
+		return
+	}
+
+	// body 可省略，預設匯出 ZIP
+	var req dto.DataExportDTO
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			var ve validator.ValidationErrors
+			if errors.As(err, &ve) {
+				utils.ReturnError(c, utils.CodeParamInvalid, utils.ExtractFieldErrorMessages(req, ve), "欄位驗證失敗")
+				return
+			}
+			utils.ReturnError(c, utils.CodeParamInvalid, err.Error())
+			return
+		}
+	}
+
+	job, err := h.userService.RequestExport(c.Request.Context(), userID, req.Format)
+	if err != nil {
+		log.Printf("❌ 建立個資匯出工作失敗 (user %d)：%v", userID, err)
+		utils.ReturnError(c, utils.CodeServerError, nil, "建立匯出工作失敗")
+		return
+	}
+	utils.ReturnSuccess(c, job, "匯出處理中，請以工作 ID 查詢進度")
+}
+
+// EraseData 申請刪除自己的個資：帳號立即刪除並匿名化，無法復原
+func (h *Handler) EraseData(c *gin.Context) {
+	var req dto.DataEraseDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		var ve validator.ValidationErrors
+		if errors.As(err, &ve) {
+			utils.ReturnError(c, utils.CodeParamInvalid, utils.ExtractFieldErrorMessages(req, ve), "欄位驗證失敗")
+			return
+		}
+		utils.ReturnError(c, utils.CodeParamInvalid, err.Error())
+		return
+	}
+
+	actor := audit.ActorFromContext(c)
+	if actor.ID == 0 {
+		utils.ReturnError(c, utils.CodeUnauthorized, nil, "無法取得使用者資訊")
+		return
+	}
+
+	job, err := h.userService.RequestErase(c.Request.Context(), actor, req.CurrentPassword)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrWrongPassword):
+			utils.ReturnError(c, utils.CodeInvalidCredentials, nil, "目前密碼錯誤")
+		case errors.Is(err, ErrTooManyAttempts):
+			utils.ReturnError(c, utils.CodeTooManyRequests, nil, "目前密碼錯誤次數過多，請稍後再試")
+		case errors.Is(err, ErrUserNotFound):
+			utils.ReturnError(c, utils.CodeNotFound, nil, "找不到使用者")
+		default:
+			log.Printf("❌ 建立個資刪除工作失敗 (user %d)：%v", actor.ID, err)
+			utils.ReturnError(c, utils.CodeServerError, nil, "建立刪除工作失敗")
+		}
+		return
+	}
+	utils.ReturnSuccess(c, job, "個資刪除處理中，完成後所有裝置都會登出")
+}
+
+// GetDataRequest 查詢自己的個資工作狀態
+func (h *Handler) GetDataRequest(c *gin.Context) {
+	userID, _ := utils.GetUserID(c)
+	job, err := h.userService.GetDataRequest(c.Request.Context(), c.Param("id"))
+	if err != nil || job.UserID != userID {
+		utils.ReturnError(c, utils.CodeNotFound, nil, "找不到此工作")
+		return
+	}
+	utils.ReturnSuccess(c, job)
+}
+
+// DownloadDataExport 下載已完成的個資匯出檔
+func (h *Handler) DownloadDataExport(c *gin.Context) {
+	userID, _ := utils.GetUserID(c)
+	path, filename, err := h.userService.ExportFile(c.Request.Context(), userID, c.Param("id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrExportNotReady):
+			utils.ReturnError(c, utils.CodeNotFound, nil, "匯出檔尚未完成或已過期")
+		default:
+			utils.ReturnError(c, utils.CodeNotFound, nil, "找不到此工作")
+		}
+		return
+	}
+	c.FileAttachment(path, filename)
+}
+
+// AdminGetDataRequest 管理者查詢任何個資工作的狀態（刪除完成後本人已無法登入查詢）
+func (h *Handler) AdminGetDataRequest(c *gin.Context) {
+	job, err := h.userService.GetDataRequest(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		utils.ReturnError(c, utils.CodeNotFound, nil, "找不到此工作")
+		return
+	}
+	utils.ReturnSuccess(c, job)
+}
@@ -0,0 +1,186 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+	"log"
+	"micro-golang/internal/audit"
+	"micro-golang/internal/config"
+	"micro-golang/internal/dto"
+	"micro-golang/internal/mail"
+	"micro-golang/internal/models"
+	"strconv"
+	"time"
+)
+
+/**
+ * @File: email_change.go
+ * @Description:
+ *
+ * 變更 Email：新 Email 需點擊確認連結後才會生效，並通知原 Email
+ * 同一使用者只保留最新一次申請，舊的確認連結會失效；確認時再檢查一次新 Email 是否已被使用。
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/24 上午10:30
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+const (
+	emailChangePrefix     = "email_change:"      // email_change:<token hash> → pendingEmailChange
+	emailChangeUserPrefix = "email_change:user:" // email_change:user:<userId> → 最新一次申請的 token hash
+	emailChangeTTL        = 24 * time.Hour
+)
+
+var ErrInvalidEmailChangeToken = errors.New("invalid or expired email change token")
+
+// pendingEmailChange 待確認的 Email 變更
+type pendingEmailChange struct {
+	UserID   uint   `json:"user_id"`
+	NewEmail string `json:"new_email"`
+}
+
+func emailChangeUserKey(userID uint) string {
+	return emailChangeUserPrefix + strconv.FormatUint(uint64(userID), 10)
+}
+
+// requestEmailChange 記錄待確認的變更，寄確認信到新 Email 並通知原 Email
+func (s *Service) requestEmailChange(ctx context.Context, user models.User, newEmail string) error {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	sum := sha256.Sum256([]byte(token))
+	hash := hex.EncodeToString(sum[:])
+
+	value, err := json.Marshal(pendingEmailChange{UserID: user.ID, NewEmail: newEmail})
+	if err != nil {
+		return err
+	}
+	userKey := emailChangeUserKey(user.ID)
+	previous, err := s.rdb.Get(ctx, userKey).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return err
+	}
+	pipe := s.rdb.TxPipeline()
+	if previous != "" {
+		pipe.Del(ctx, emailChangePrefix+previous)
+	}
+	pipe.Set(ctx, emailChangePrefix+hash, value, emailChangeTTL)
+	pipe.Set(ctx, userKey, hash, emailChangeTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	link := config.GetEnv("AUTH_BASE_URL", "http://localhost:7001") + "/auth/email-change/confirm?token=" + token
+	if err := s.mailer.Send(ctx, mail.Message{
+		To:      newEmail,
+		Subject: "請確認您的新 Email",
+		Body: fmt.Sprintf("%s 您好：\n\n您申請將帳號 Email 變更為此信箱，請在 %d 小時內點擊以下連結完成確認：\n%s\n\n若您沒有提出申請，請忽略這封信。",
+			user.Username, int(emailChangeTTL.Hours()), link),
+	}); err != nil {
+		return err
+	}
+	// 通知信寄送失敗不影響申請，使用者仍可完成確認
+	if err := s.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "帳號 Email 變更申請",
+		Body: fmt.Sprintf("%s 您好：\n\n您的帳號剛申請將 Email 變更為 %s，確認後將改用新 Email 登入。\n\n若這不是您本人的操作，請立即變更密碼並登出所有裝置。",
+			user.Username, newEmail),
+	}); err != nil {
+		log.Printf("⚠️ 寄送 Email 變更通知失敗：%v", err)
+	}
+	return nil
+}
+
+// ConfirmEmailChange 以確認 token 完成 Email 變更，token 只能使用一次
+// 已發出的 token 以 userId 識別身分，變更後仍然有效
+func (s *Service) ConfirmEmailChange(ctx context.Context, token string) (*dto.UserLoginResponseDTO, error) {
+	sum := sha256.Sum256([]byte(token))
+	hash := hex.EncodeToString(sum[:])
+	raw, err := s.rdb.GetDel(ctx, emailChangePrefix+hash).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrInvalidEmailChangeToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	var pending pendingEmailChange
+	if err := json.Unmarshal([]byte(raw), &pending); err != nil {
+		return nil, ErrInvalidEmailChangeToken
+	}
+	// 只接受最新一次申請
+	userKey := emailChangeUserKey(pending.UserID)
+	if latest, _ := s.rdb.Get(ctx, userKey).Result(); latest != hash {
+		return nil, ErrInvalidEmailChangeToken
+	}
+	s.rdb.Del(ctx, userKey)
+
+	var user models.User
+	var oldEmail string
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&user, pending.UserID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidEmailChangeToken
+			}
+			return err
+		}
+		// 申請後到確認前，新 Email 可能已被註冊（已刪除但尚未匿名化的帳號仍佔用 Email）
+		var count int64
+		if err := tx.Unscoped().Model(&models.User{}).
+			Where("email = ? AND id != ?", pending.NewEmail, user.ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrEmailInUse
+		}
+
+		oldEmail = user.Email
+		now := time.Now()
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"email":             pending.NewEmail,
+			"email_verified_at": now,
+		}).Error; err != nil {
+			return err
+		}
+		user.Email = pending.NewEmail
+		return audit.Record(tx, audit.Entry{
+			Actor:      audit.Actor{ID: user.ID, Role: user.Role},
+			Action:     audit.ActionEmailChange,
+			TargetType: "user",
+			TargetID:   strconv.FormatUint(uint64(user.ID), 10),
+			Detail:     map[string]interface{}{"from": oldEmail, "to": pending.NewEmail},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 快取以 ID 為 key，直接改寫成新資料
+	profile := dto.UserLoginResponseDTO{
+		ID:       user.ID,
+		Email:    user.Email,
+		Username: user.Username,
+		Role:     user.Role,
+	}
+	_ = s.cacheProfile(config.Ctx, profile)
+
+	if err := s.mailer.Send(ctx, mail.Message{
+		To:      oldEmail,
+		Subject: "帳號 Email 已變更",
+		Body: fmt.Sprintf("%s 您好：\n\n您的帳號 Email 已變更為 %s，之後請使用新 Email 登入。\n\n若這不是您本人的操作，請立即聯絡客服。",
+			user.Username, user.Email),
+	}); err != nil {
+		log.Printf("⚠️ 寄送 Email 變更通知失敗：%v", err)
+	}
+	return &profile, nil
+}
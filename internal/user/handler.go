@@ -1,17 +1,14 @@
 package user
 
 import (
-	"encoding/json"
 	"errors"
 	"github.com/gin-gonic/gin"
 	"log"
-	"micro-golang/internal/config"
 	"micro-golang/internal/dto"
-	"micro-golang/internal/models"
 	"micro-golang/internal/utils"
 	"net/http"
+	"strconv"
 	"strings"
-	"time"
 )
 
 /**
@@ -33,73 +30,77 @@ func NewHandler(userService *Service) *Handler {
 	return &Handler{userService: userService}
 }
 
+// GetUser 依 ID 查詢使用者（一般使用者只能查自己，路由需搭配 RequireSelfOrPermission）
 func (h *Handler) GetUser(c *gin.Context) {
-	// 模擬 DB
-	id := c.Param("id")
-	c.JSON(http.StatusOK, gin.H{
-		"id":   id,
-		"name": "User" + id,
-	})
+	profile, ok := h.userByParam(c)
+	if !ok {
+		return
+	}
+	utils.ReturnSuccess(c, profile)
 }
 
+// GetUserEmail 依 ID 查詢使用者 Email
 func (h *Handler) GetUserEmail(c *gin.Context) {
-	// 模擬 DB
-	id := c.Param("id")
-	c.JSON(http.StatusOK, gin.H{
-		"email": id + "@example.com",
-	})
+	profile, ok := h.userByParam(c)
+	if !ok {
+		return
+	}
+	utils.ReturnSuccess(c, dto.UserEmailDTO{ID: profile.ID, Email: profile.Email})
+}
+
+// userByParam 以路徑參數 id 查詢使用者，失敗時已回應錯誤
+func (h *Handler) userByParam(c *gin.Context) (*dto.UserLoginResponseDTO, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		utils.ReturnError(c, utils.CodeParamInvalid, nil, "使用者 ID 格式錯誤")
+		return nil, false
+	}
+	profile, _, err := h.userService.GetUserByID(c.Request.Context(), uint(id))
+	if errors.Is(err, ErrUserNotFound) {
+		utils.ReturnError(c, utils.CodeNotFound, nil, "使用者不存在")
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("❌ 查詢使用者失敗 (user %d)：%v", id, err)
+		utils.ReturnError(c, utils.CodeServerError, nil, "查詢使用者失敗")
+		return nil, false
+	}
+	return profile, true
 }
 
 // GetProfile 獲取用戶基本資料
+// 以 token 中不會變動的 userId 查詢，變更 Email 後舊 token 仍可使用
 func (h *Handler) GetProfile(c *gin.Context) {
-	emailVal, exists := c.Get("email")
+	userID, exists := utils.GetUserID(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "No email in token"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "No user id in token"})
 		return
 	}
-	email := emailVal.(string)
 
-	// 1️⃣ 先從 Redis 查快取
-	cacheKey := "user:" + email
-	cached, err := config.RDB.Get(config.Ctx, cacheKey).Result()
-	if err == nil {
-		// 如果有快取，直接回傳
-		var cachedUser dto.UserLoginResponseDTO
-		// json.Unmarshal 將資料JSON格式化
-		if err := json.Unmarshal([]byte(cached), &cachedUser); err == nil {
-			utils.ReturnSuccess(c, cachedUser, "from cache")
-			return
-		}
-	}
-
-	// 2️⃣ 沒快取，查 DB
-	var user models.User
-	result := config.DB.Where("email = ?", email).First(&user)
-	if result.Error != nil {
+	profile, fromCache, err := h.userService.GetUserByID(c.Request.Context(), userID)
+	if errors.Is(err, ErrUserNotFound) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
-
-	// 3️⃣ 查到後，存入 Redis 快取（設 10 分鐘過期）
-	safeUser := dto.UserLoginResponseDTO{
-		ID:       user.ID,
-		Email:    user.Email,
-		Username: user.Username,
-		Role:     user.Role,
+	if err != nil {
+		log.Printf("❌ 查詢個人資料失敗 (user %d)：%v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user profile"})
+		return
+	}
+	if fromCache {
+		utils.ReturnSuccess(c, profile, "from cache")
+		return
 	}
-	userBytes, _ := json.Marshal(safeUser)
-	config.RDB.Set(config.Ctx, cacheKey, userBytes, 10*time.Minute)
-	utils.ReturnSuccess(c, safeUser, "from db")
+	utils.ReturnSuccess(c, profile, "from db")
 }
 
 // UpdateProfile 更新用戶基本資料 (Username, Email) - 調用 Service
 func (h *Handler) UpdateProfile(c *gin.Context) {
-	emailVal, exists := c.Get("email")
+	userID, exists := utils.GetUserID(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: No email in token"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: No user id in token"})
 		return
 	}
-	currentEmail := emailVal.(string)
 
 	var req dto.UserUpdateProfileDTO
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -109,11 +110,17 @@ func (h *Handler) UpdateProfile(c *gin.Context) {
 
 	// 調用 service 方法
 	// 使用 c.Request.Context() 將請求上下文傳遞給 service 層，這對於超時控制和值傳遞很有用
-	updatedUserDTO, err := h.userService.UpdateUserProfile(c.Request.Context(), currentEmail, req)
+	updatedUserDTO, err := h.userService.UpdateUserProfile(c.Request.Context(), userID, req)
 	if err != nil {
 		switch {
 		case errors.Is(err, ErrUserNotFound):
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, ErrPasswordRequired):
+			utils.ReturnError(c, utils.CodeParamInvalid, map[string]string{"current_password": "變更 Email 需輸入目前密碼"}, "欄位驗證失敗")
+		case errors.Is(err, ErrWrongPassword):
+			utils.ReturnError(c, utils.CodeInvalidCredentials, nil, "目前密碼錯誤")
+		case errors.Is(err, ErrTooManyAttempts):
+			utils.ReturnError(c, utils.CodeTooManyRequests, nil, "目前密碼錯誤次數過多，請稍後再試")
 		case errors.Is(err, ErrEmailInUse):
 			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
 		case errors.Is(err, ErrUpdateNoChanges):
@@ -138,5 +145,9 @@ func (h *Handler) UpdateProfile(c *gin.Context) {
 		return
 	}
 
+	if updatedUserDTO.PendingEmail != "" {
+		utils.ReturnSuccess(c, updatedUserDTO, "確認信已寄至新 Email，點擊信中連結後才會變更")
+		return
+	}
 	utils.ReturnSuccess(c, updatedUserDTO, "User profile updated successfully")
 }
@@ -0,0 +1,61 @@
+package user
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"log"
+	"micro-golang/internal/dto"
+	"micro-golang/internal/password"
+	"micro-golang/internal/utils"
+)
+
+/**
+ * @File: password_handler.go
+ * @Description:
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/22 下午2:40
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// ChangePassword 變更密碼（PUT /users/password），成功後需重新登入
+func (h *Handler) ChangePassword(c *gin.Context) {
+	userID, ok := utils.GetUserID(c)
+	if !ok {
+		utils.ReturnError(c, utils.CodeUnauthorized, nil, "無法取得使用者資訊")
+		return
+	}
+
+	var req dto.PasswordChangeDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		var ve validator.ValidationErrors
+		if errors.As(err, &ve) {
+			utils.ReturnError(c, utils.CodeParamInvalid, utils.ExtractFieldErrorMessages(req, ve), "欄位驗證失敗")
+			return
+		}
+		utils.ReturnError(c, utils.CodeParamInvalid, err.Error())
+		return
+	}
+
+	err := h.userService.ChangePassword(c.Request.Context(), userID, req.CurrentPassword, req.Password)
+	if err != nil {
+		var policyErr *password.PolicyError
+		switch {
+		case errors.As(err, &policyErr):
+			utils.ReturnError(c, utils.CodeParamInvalid, policyErr.Messages("password"), "欄位驗證失敗")
+		case errors.Is(err, ErrWrongPassword):
+			utils.ReturnError(c, utils.CodeInvalidCredentials, nil, "目前密碼錯誤")
+		case errors.Is(err, ErrTooManyAttempts):
+			utils.ReturnError(c, utils.CodeTooManyRequests, nil, "目前密碼錯誤次數過多，請稍後再試")
+		case errors.Is(err, ErrUserNotFound):
+			utils.ReturnError(c, utils.CodeNotFound, nil, "找不到使用者")
+		default:
+			log.Printf("❌ 變更密碼失敗 (user %d)：%v", userID, err)
+			utils.ReturnError(c, utils.CodeServerError, nil, "變更密碼失敗")
+		}
+		return
+	}
+	utils.ReturnSuccess(c, nil, "密碼已變更，請以新密碼重新登入")
+}
@@ -0,0 +1,78 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"micro-golang/internal/models"
+	"micro-golang/internal/password"
+	"strconv"
+	"time"
+)
+
+/**
+ * @File: password_service.go
+ * @Description:
+ *
+ * 登入後變更密碼
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/22 下午2:20
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+const (
+	passwordChangeAttemptPrefix = "rate:password_change:" // rate:password_change:<userId> → 目前密碼錯誤次數（變更密碼、刪除帳號共用）
+	passwordChangeMaxAttempts   = 5
+	passwordChangeWindow        = 15 * time.Minute
+)
+
+var (
+	ErrWrongPassword   = errors.New("current password is incorrect")
+	ErrTooManyAttempts = errors.New("too many attempts")
+)
+
+// ChangePassword 驗證目前密碼後設定新密碼，成功後所有已發出的 token 都會失效（需重新登入）
+func (s *Service) ChangePassword(ctx context.Context, userID uint, current string, newPassword string) error {
+	user, err := s.verifyCurrentPassword(ctx, userID, current)
+	if err != nil {
+		return err
+	}
+
+	if err := password.Validate(newPassword, user.Email, user.Username); err != nil {
+		return err
+	}
+	if err := password.CheckReuse(ctx, s.db, user.ID, user.Password, newPassword); err != nil {
+		return err
+	}
+	return password.Update(ctx, s.db, user.ID, newPassword)
+}
+
+// verifyCurrentPassword 敏感操作前再次確認目前密碼（變更密碼、變更 Email、刪除帳號、個資請求共用）
+// 目前密碼錯誤次數有限制，避免拿到 access token 的人藉此猜測密碼
+func (s *Service) verifyCurrentPassword(ctx context.Context, userID uint, current string) (*models.User, error) {
+	attemptKey := passwordChangeAttemptPrefix + strconv.FormatUint(uint64(userID), 10)
+	if n, _ := s.rdb.Get(ctx, attemptKey).Int64(); n >= passwordChangeMaxAttempts {
+		return nil, ErrTooManyAttempts
+	}
+
+	var user models.User
+	if err := s.db.WithContext(ctx).First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	// 外部登入建立、沒有密碼的帳號無法通過，需改用忘記密碼設定
+	if ok, _ := password.Verify(current, user.Password); !ok {
+		pipe := s.rdb.TxPipeline()
+		pipe.Incr(ctx, attemptKey)
+		pipe.Expire(ctx, attemptKey, passwordChangeWindow)
+		_, _ = pipe.Exec(ctx)
+		return nil, ErrWrongPassword
+	}
+	s.rdb.Del(ctx, attemptKey)
+	return &user, nil
+}
@@ -0,0 +1,78 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"gorm.io/gorm"
+	"micro-golang/internal/dto"
+	"micro-golang/internal/models"
+	"strconv"
+	"time"
+)
+
+/**
+ * @File: profile.go
+ * @Description:
+ *
+ * 個人資料查詢（先查 Redis 快取，沒有再查 DB），usersvc 的 /users/profile、/users/:id 與 authsvc 的 /userinfo 共用
+ * 快取以不會變動的 ID（user:id:<id>）為 key，變更 Email 時不會留下舊 key。
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/20 下午2:00
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+const profileCacheTTL = 10 * time.Minute
+
+// profileIDCacheKey 以 ID 查詢的快取 key
+func profileIDCacheKey(id uint) string {
+	return "user:id:" + strconv.FormatUint(uint64(id), 10)
+}
+
+// GetUserByID 依 ID 取得使用者基本資料，第二個回傳值表示是否來自快取
+func (s *Service) GetUserByID(ctx context.Context, id uint) (*dto.UserLoginResponseDTO, bool, error) {
+	// 1️⃣ 先從 Redis 查快取
+	if cached, err := s.rdb.Get(ctx, profileIDCacheKey(id)).Result(); err == nil {
+		var cachedUser dto.UserLoginResponseDTO
+		// json.Unmarshal 將資料JSON格式化
+		if err := json.Unmarshal([]byte(cached), &cachedUser); err == nil {
+			return &cachedUser, true, nil
+		}
+	}
+
+	// 2️⃣ 沒快取，查 DB
+	var user models.User
+	if err := s.db.WithContext(ctx).First(&user, id).Error; err != nil {
+		// 只有查無資料才是不存在，DB 故障時回傳原本的錯誤，避免呼叫端誤判
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, false, ErrUserNotFound
+		}
+		return nil, false, err
+	}
+
+	// 3️⃣ 查到後，存入 Redis 快取（設 10 分鐘過期）
+	safeUser := dto.UserLoginResponseDTO{
+		ID:       user.ID,
+		Email:    user.Email,
+		Username: user.Username,
+		Role:     user.Role,
+	}
+	s.cacheProfile(ctx, safeUser)
+	return &safeUser, false, nil
+}
+
+// cacheProfile 寫入個人資料快取
+func (s *Service) cacheProfile(ctx context.Context, profile dto.UserLoginResponseDTO) error {
+	userBytes, err := json.Marshal(profile)
+	if err != nil {
+		return err
+	}
+	return s.rdb.Set(ctx, profileIDCacheKey(profile.ID), userBytes, profileCacheTTL).Err()
+}
+
+// invalidateProfile 資料異動後刪除快取
+func (s *Service) invalidateProfile(ctx context.Context, user models.User) {
+	s.rdb.Del(ctx, profileIDCacheKey(user.ID))
+}
@@ -2,12 +2,12 @@ package user
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"github.com/redis/go-redis/v9"
 	"gorm.io/gorm"
 	"micro-golang/internal/config"
 	"micro-golang/internal/dto"
+	"micro-golang/internal/mail"
 	"micro-golang/internal/models"
 	"strings"
 	"time"
@@ -25,15 +25,17 @@ import (
 
 // Service 負責處理用戶相關的業務邏輯
 type Service struct {
-	db  *gorm.DB
-	rdb *redis.Client // 假設你的 config.RDB 是 *redis.Client 的類型，或者你可以直接用 config.RDB
+	db     *gorm.DB
+	rdb    *redis.Client // 假設你的 config.RDB 是 *redis.Client 的類型，或者你可以直接用 config.RDB
+	mailer mail.Mailer   // 寄送 Email 變更確認信與通知
 }
 
 // NewService 創建 Service 實例
-func NewService(db *gorm.DB, rdb *redis.Client) *Service {
+func NewService(db *gorm.DB, rdb *redis.Client, mailer mail.Mailer) *Service {
 	return &Service{
-		db:  db,
-		rdb: rdb, // 或者直接在方法中使用 config.RDB
+		db:     db,
+		rdb:    rdb, // 或者直接在方法中使用 config.RDB
+		mailer: mailer,
 	}
 }
 
@@ -43,24 +45,25 @@ var (
 	ErrEmailInUse       = errors.New("email is already in use")
 	ErrUpdateNoChanges  = errors.New("no fields provided for update or no changes detected")
 	ErrValidationFailed = errors.New("validation failed") // For business rule validation
+	ErrPasswordRequired = errors.New("current password is required")
 )
 
 // UpdateUserProfile 更新用戶資料 (Username, Email)
-func (s *Service) UpdateUserProfile(ctx context.Context, currentEmail string, req dto.UserUpdateProfileDTO) (*dto.UserLoginResponseDTO, error) {
+// Username 立即生效；Email 需再次輸入目前密碼，並到新信箱點擊確認連結後才會變更，回應中以 pending_email 表示待確認
+func (s *Service) UpdateUserProfile(ctx context.Context, userID uint, req dto.UserUpdateProfileDTO) (*dto.UserProfileUpdateResponseDTO, error) {
 	if req.Username == nil && req.Email == nil {
 		return nil, ErrUpdateNoChanges
 	}
 
 	var user models.User
-	if err := s.db.WithContext(ctx).Where("email = ?", currentEmail).First(&user).Error; err != nil {
+	if err := s.db.WithContext(ctx).First(&user, userID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, ErrUserNotFound
 		}
 		return nil, err // Return generic DB error
 	}
 
-	oldCacheKey := "user:" + user.Email
-	emailChanged := false
+	pendingEmail := ""
 	updates := make(map[string]interface{})
 
 	if req.Username != nil && *req.Username != user.Username {
@@ -74,8 +77,17 @@ func (s *Service) UpdateUserProfile(ctx context.Context, currentEmail string, re
 		newEmail := strings.ToLower(*req.Email)
 		// TODO: 在此處添加更詳細的 Email 業務驗證邏輯 (如果需要)
 
+		// 先確認密碼再檢查 Email 是否被使用，避免拿到 access token 的人藉此探測帳號
+		if req.CurrentPassword == "" {
+			return nil, ErrPasswordRequired
+		}
+		if _, err := s.verifyCurrentPassword(ctx, user.ID, req.CurrentPassword); err != nil {
+			return nil, err
+		}
+
 		var existingUserWithNewEmail models.User
-		err := s.db.WithContext(ctx).Where("email = ? AND id != ?", newEmail, user.ID).First(&existingUserWithNewEmail).Error
+		// 已刪除但尚未匿名化的帳號仍佔用 Email
+		err := s.db.WithContext(ctx).Unscoped().Where("email = ? AND id != ?", newEmail, user.ID).First(&existingUserWithNewEmail).Error
 		if err == nil {
 			return nil, ErrEmailInUse
 		}
@@ -83,56 +95,44 @@ func (s *Service) UpdateUserProfile(ctx context.Context, currentEmail string, re
 			return nil, err // DB 查詢本身出錯
 		}
 
-		updates["email"] = newEmail
-		user.Email = newEmail
-		emailChanged = true
+		// Email 先不變更，等新信箱確認後才生效
+		pendingEmail = newEmail
 	}
-	
-	// 如果真的沒有任何欄位被賦予新值 (DTO 有值但與 DB 相同，或 DTO 欄位為 nil)
-	if len(updates) == 0 {
-		// 返回當前用戶信息，表示沒有實際更改
-		safeUser := dto.UserLoginResponseDTO{
+
+	updatedSafeUserDTO := dto.UserProfileUpdateResponseDTO{
+		UserLoginResponseDTO: dto.UserLoginResponseDTO{
 			ID:       user.ID,
 			Email:    user.Email,
 			Username: user.Username,
 			Role:     user.Role,
-		}
-		return &safeUser, ErrUpdateNoChanges // 使用一個特定的 error 或 nil 來表示無變更但操作成功
+		},
+		PendingEmail: pendingEmail,
 	}
 
-	updates["updated_at"] = time.Now() // GORM 通常會自動處理，但顯式指定也無妨
-
-	if err := s.db.WithContext(ctx).Model(&user).Updates(updates).Error; err != nil {
-		return nil, err // Return generic DB error
+	// 如果真的沒有任何欄位被賦予新值 (DTO 有值但與 DB 相同，或 DTO 欄位為 nil)
+	if len(updates) == 0 && pendingEmail == "" {
+		// 返回當前用戶信息，表示沒有實際更改
+		return &updatedSafeUserDTO, ErrUpdateNoChanges // 使用一個特定的 error 或 nil 來表示無變更但操作成功
 	}
 
-	// 更新快取邏輯
-	if emailChanged {
-		_, errCacheDel := s.rdb.Del(config.Ctx, oldCacheKey).Result() // 使用注入的 rdb 或全局的 config.Ctx
-		if errCacheDel != nil {
-			// Log cache deletion error, but proceed as DB update was successful
-			// log.Printf("Warning: Failed to delete old cache key %s: %v", oldCacheKey, errCacheDel)
+	if pendingEmail != "" {
+		if err := s.requestEmailChange(ctx, user, pendingEmail); err != nil {
+			return nil, err
 		}
 	}
-
-	newCacheKey := "user:" + user.Email
-	updatedSafeUserDTO := dto.UserLoginResponseDTO{
-		ID:       user.ID,
-		Email:    user.Email,
-		Username: user.Username,
-		Role:     user.Role,
+	if len(updates) == 0 {
+		return &updatedSafeUserDTO, nil
 	}
-	userBytes, err := json.Marshal(updatedSafeUserDTO)
-	if err != nil {
-		// Log marshalling error, proceed but indicate cache issue
-		// log.Printf("Warning: Failed to marshal user data for cache: %v", err)
-		return &updatedSafeUserDTO, errors.New("user updated successfully, but cache marshal failed")
+
+	updates["updated_at"] = time.Now() // GORM 通常會自動處理，但顯式指定也無妨
+
+	if err := s.db.WithContext(ctx).Model(&user).Updates(updates).Error; err != nil {
+		return nil, err // Return generic DB error
 	}
 
-	err = s.rdb.Set(config.Ctx, newCacheKey, userBytes, 10*time.Minute).Err()
-	if err != nil {
+	if err := s.cacheProfile(config.Ctx, updatedSafeUserDTO.UserLoginResponseDTO); err != nil {
 		// Log cache set error
-		// log.Printf("Warning: Failed to set user cache for %s: %v", newCacheKey, err)
+		// log.Printf("Warning: Failed to set user cache for %s: %v", user.Email, err)
 		return &updatedSafeUserDTO, errors.New("user updated successfully, but cache set failed")
 	}
 
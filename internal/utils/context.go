@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+/**
+ * @File: context.go
+ * @Description:
+ *
+ * 讀取 JWTAuth 放進 gin.Context 的使用者資訊
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/18 上午11:20
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// GetActorID 代理登入時取出實際操作的管理者 userId，非代理登入時回傳 false
+func GetActorID(c *gin.Context) (uint, bool) {
+	id, ok := c.Get("actorId")
+	if !ok {
+		return 0, false
+	}
+	actorID, ok := id.(uint)
+	return actorID, ok && actorID > 0
+}
+
+// GetUserID 取出目前登入者的 userId
+// JWTAuth 直接把 claims 放進 context，數字會是 float64，這裡統一轉成 uint
+func GetUserID(c *gin.Context) (uint, bool) {
+	val, exists := c.Get("userId")
+	if !exists {
+		return 0, false
+	}
+	switch id := val.(type) {
+	case float64:
+		return uint(id), id > 0
+	case uint:
+		return id, id > 0
+	default:
+		return 0, false
+	}
+}
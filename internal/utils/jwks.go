@@ -0,0 +1,219 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"log"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+/**
+ * @File: jwks.go
+ * @Description:
+ *
+ * JWKS（JSON Web Key Set）格式轉換，以及 usersvc / ordersvc 用來驗章的 JWKS 快取客戶端
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/18 下午1:45
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// JWK 單一公鑰（RFC 7517），RSA 使用 n/e，Ed25519 使用 crv/x
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS 公鑰集合
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewJWK 將公鑰轉為 JWK
+func NewJWK(kid string, pub crypto.PublicKey) (JWK, error) {
+	switch key := pub.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			Kid: kid,
+			Use: "sig",
+			Alg: "RS256",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}, nil
+	case ed25519.PublicKey:
+		return JWK{
+			Kty: "OKP",
+			Kid: kid,
+			Use: "sig",
+			Alg: "EdDSA",
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(key),
+		}, nil
+	default:
+		return JWK{}, fmt.Errorf("unsupported public key type %T", pub)
+	}
+}
+
+// PublicKey 將 JWK 還原為公鑰
+func (k JWK) PublicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// jwksMinRefresh 遇到未知 kid 時重新抓取 JWKS 的最短間隔，避免被亂填 kid 的 token 打爆 authsvc
+const jwksMinRefresh = 10 * time.Second
+
+// JWKSClient 從遠端抓取 JWKS 並快取
+type JWKSClient struct {
+	url        string
+	ttl        time.Duration
+	httpClient *http.Client
+
+	mu          sync.RWMutex
+	keys        map[string]JWK
+	fetchedAt   time.Time // 上次成功抓取的時間，用來判斷快取是否過期
+	lastAttempt time.Time // 上次嘗試抓取的時間，用來限制抓取頻率
+	inflight    *jwksCall // 進行中的抓取，同時間的其他請求等待同一個結果
+}
+
+// jwksCall 一次進行中的抓取
+type jwksCall struct {
+	done chan struct{}
+	err  error
+}
+
+// NewJWKSClient 建立 JWKS 客戶端，ttl 為快取有效時間
+func NewJWKSClient(url string, ttl time.Duration) *JWKSClient {
+	return &JWKSClient{
+		url:        url,
+		ttl:        ttl,
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+		keys:       map[string]JWK{},
+	}
+}
+
+// Keyfunc 供 jwt.Parse 使用；快取過期或遇到未知 kid（金鑰剛輪替）時會重新抓取
+func (jc *JWKSClient) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	jwk, ok, stale := jc.lookup(kid)
+	if !ok || stale {
+		if err := jc.refresh(); err != nil {
+			// 抓不到時沿用舊快取，authsvc 短暫不可用不影響驗章
+			log.Printf("⚠️ 無法更新 JWKS：%v", err)
+		}
+		jwk, ok, _ = jc.lookup(kid)
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown kid %q", kid)
+	}
+	if jwk.Alg != "" && jwk.Alg != token.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s for kid %s", token.Method.Alg(), kid)
+	}
+	return jwk.PublicKey()
+}
+
+func (jc *JWKSClient) lookup(kid string) (JWK, bool, bool) {
+	jc.mu.RLock()
+	defer jc.mu.RUnlock()
+	jwk, ok := jc.keys[kid]
+	return jwk, ok, time.Since(jc.fetchedAt) > jc.ttl
+}
+
+// refresh 重新抓取 JWKS，距離上次抓取太近時直接略過
+// 同時間只會有一個請求真的去抓，其他請求等待同一次結果；抓取期間不持有鎖，不會卡住使用快取驗章的請求
+func (jc *JWKSClient) refresh() error {
+	jc.mu.Lock()
+	if call := jc.inflight; call != nil {
+		jc.mu.Unlock()
+		<-call.done
+		return call.err
+	}
+	if time.Since(jc.lastAttempt) < jwksMinRefresh {
+		jc.mu.Unlock()
+		return nil
+	}
+	jc.lastAttempt = time.Now()
+	call := &jwksCall{done: make(chan struct{})}
+	jc.inflight = call
+	jc.mu.Unlock()
+
+	keys, err := jc.fetch()
+
+	jc.mu.Lock()
+	if err == nil {
+		jc.keys = keys
+		jc.fetchedAt = time.Now()
+	}
+	jc.inflight = nil
+	jc.mu.Unlock()
+
+	call.err = err
+	close(call.done)
+	return err
+}
+
+// fetch 抓取並解析 JWKS
+func (jc *JWKSClient) fetch() (map[string]JWK, error) {
+	resp, err := jc.httpClient.Get(jc.url)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jwks 請求失敗，狀態碼: %d", resp.StatusCode)
+	}
+
+	var set JWKS
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]JWK, len(set.Keys))
+	for _, k := range set.Keys {
+		keys[k.Kid] = k
+	}
+	return keys, nil
+}
@@ -1,10 +1,10 @@
 package utils
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
-	"fmt"
 	"github.com/golang-jwt/jwt/v5"
-	"os"
 	"time"
 )
 
@@ -18,20 +18,61 @@ import (
  * @Version:  1.0
  */
 
-var JwtKey = []byte(os.Getenv("JWT_SECRET"))
+var (
+	signingKeys *KeySet     // 簽章用金鑰，只有 authsvc 會設定
+	keyfunc     jwt.Keyfunc // 驗章時取得公鑰的方式
+)
+
+// UseSigningKeys 設定簽章用的 KeySet，並直接以本地公鑰驗章（authsvc 使用）
+func UseSigningKeys(ks *KeySet) {
+	signingKeys = ks
+	keyfunc = ks.Keyfunc
+}
+
+// UseJWKS 以遠端 JWKS 驗章（usersvc / ordersvc 使用）
+func UseJWKS(client *JWKSClient) {
+	keyfunc = client.Keyfunc
+}
+
+// SigningKeys 取得目前的簽章 KeySet，未設定時為 nil
+func SigningKeys() *KeySet {
+	return signingKeys
+}
+
+const (
+	// AccessTokenTTL access token 壽命
+	AccessTokenTTL = 30 * time.Second
+	// RefreshTokenTTL refresh token 壽命
+	RefreshTokenTTL = 24 * time.Hour
+)
+
+// NewTokenID 產生隨機的 Token 識別碼（jti、family ID 使用）
+func NewTokenID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
 
 // GenerateJWT 生成Token
-func GenerateJWT(email string, userId uint, role string) (string, string, error) {
-	fmt.Println("🔐 JWT_SECRET in Login =", os.Getenv("JWT_SECRET"))
+// familyID 為 refresh token 家族 ID（同一次登入輪替出來的 token 共用），jti 為這次簽出的 refresh token 唯一 ID
+// grant 為 OAuth 授權資訊（cid、scope），會同時放進 access / refresh token，一般登入傳 nil
+func GenerateJWT(email string, userId uint, role string, familyID string, jti string, grant jwt.MapClaims) (string, string, error) {
+	// iat 用來判斷 token 是否在變更密碼之前發出
+	now := time.Now()
+
 	// 1️⃣ Access Token - 壽命短（2 小時）
 	accessClaims := jwt.MapClaims{
 		"email":  email,
 		"userId": userId,
 		"role":   role,
-		"exp":    time.Now().Add(30 * time.Second).Unix(),
+		"fid":    familyID,
+		"iat":    now.Unix(),
+		"exp":    now.Add(AccessTokenTTL).Unix(),
+	}
+	for k, v := range grant {
+		accessClaims[k] = v
 	}
-	accessTokenObj := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims)
-	accessToken, err := accessTokenObj.SignedString(JwtKey)
+	accessToken, err := SignClaims(accessClaims)
 	if err != nil {
 		return "", "", err
 	}
@@ -39,12 +80,18 @@ func GenerateJWT(email string, userId uint, role string) (string, string, error)
 	// 2️⃣ Refresh Token - 壽命長（7 天）
 	refreshClaims := jwt.MapClaims{
 		"email":      email,
+		"userId":     userId,
 		"token_type": "refresh", // 來辨別refresh 提供Refresh的API使用
-		"exp":        time.Now().Add(24 * time.Hour).Unix(),
+		"jti":        jti,       // 每張 refresh token 只能使用一次
+		"fid":        familyID,  // 重複使用時整個家族一起撤銷
+		"iat":        now.Unix(),
+		"exp":        now.Add(RefreshTokenTTL).Unix(),
+	}
+	for k, v := range grant {
+		refreshClaims[k] = v
 	}
 
-	refreshTokenObj := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims)
-	refreshToken, err := refreshTokenObj.SignedString(JwtKey)
+	refreshToken, err := SignClaims(refreshClaims)
 	if err != nil {
 		return "", "", err
 	}
@@ -52,11 +99,23 @@ func GenerateJWT(email string, userId uint, role string) (string, string, error)
 	return accessToken, refreshToken, nil
 }
 
+// SignClaims 以目前啟用中的金鑰簽章，header 帶上 kid 讓驗章端找到對應公鑰
+func SignClaims(claims jwt.MapClaims) (string, error) {
+	if signingKeys == nil {
+		return "", errors.New("jwt signing keys not configured")
+	}
+	key := signingKeys.Active()
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Private)
+}
+
 // ParseToken 解析 JWT 並回傳 claims（不做類型轉換）
 func ParseToken(tokenString string) (jwt.MapClaims, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return JwtKey, nil
-	})
+	if keyfunc == nil {
+		return nil, errors.New("jwt verifier not configured")
+	}
+	token, err := jwt.Parse(tokenString, keyfunc, jwt.WithValidMethods([]string{"RS256", "EdDSA"}))
 
 	if err != nil {
 		return nil, err
@@ -69,3 +128,12 @@ func ParseToken(tokenString string) (jwt.MapClaims, error) {
 
 	return nil, errors.New("invalid token")
 }
+
+// ClaimUserID 從 claims 取出 userId（JSON 數字解析後為 float64）
+func ClaimUserID(claims jwt.MapClaims) (uint, bool) {
+	id, ok := claims["userId"].(float64)
+	if !ok || id <= 0 {
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,281 @@
+package utils
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/**
+ * @File: jwt_keys.go
+ * @Description:
+ *
+ * JWT 非對稱簽章金鑰管理（authsvc 使用）
+ * 金鑰以 PKCS#8 PEM 存放在金鑰目錄，檔名即 kid；最新的一把負責簽章，
+ * 舊金鑰在退役後仍保留 overlap 時間供驗章，之後才從目錄移除。
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/18 下午1:20
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+// SigningKey 一把簽章用私鑰
+type SigningKey struct {
+	ID        string
+	Method    jwt.SigningMethod
+	Private   crypto.Signer
+	CreatedAt time.Time
+}
+
+// Public 取得對應的公鑰
+func (k *SigningKey) Public() crypto.PublicKey {
+	return k.Private.Public()
+}
+
+// KeySet 多把簽章金鑰，依建立時間由舊到新排序，最後一把為啟用中的金鑰
+type KeySet struct {
+	mu       sync.RWMutex
+	dir      string
+	alg      string        // 新產生金鑰使用的演算法（RS256 / EdDSA）
+	rotation time.Duration // 啟用中的金鑰使用多久後換新
+	overlap  time.Duration // 金鑰退役後仍可驗章的時間，需 ≥ token 最長壽命
+	keys     []*SigningKey
+}
+
+// LoadKeySet 從金鑰目錄載入所有金鑰，若沒有金鑰或啟用中的金鑰已到期則產生新的
+func LoadKeySet(dir string, alg string, rotation time.Duration, overlap time.Duration) (*KeySet, error) {
+	if alg != "RS256" && alg != "EdDSA" {
+		return nil, fmt.Errorf("unsupported jwt signing alg %q", alg)
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	ks := &KeySet{dir: dir, alg: alg, rotation: rotation, overlap: overlap}
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if err := ks.reload(); err != nil {
+		return nil, err
+	}
+	if err := ks.rotateIfDue(); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// Active 取得目前負責簽章的金鑰
+func (ks *KeySet) Active() *SigningKey {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return ks.keys[len(ks.keys)-1]
+}
+
+// Keyfunc 供 jwt.Parse 使用，依 header 的 kid 找出對應公鑰
+func (ks *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	for _, k := range ks.keys {
+		if k.ID == kid {
+			if token.Method.Alg() != k.Method.Alg() {
+				return nil, fmt.Errorf("unexpected signing method %s for kid %s", token.Method.Alg(), kid)
+			}
+			return k.Public(), nil
+		}
+	}
+	return nil, fmt.Errorf("unknown kid %q", kid)
+}
+
+// JWKS 輸出目前所有仍可驗章的公鑰
+func (ks *KeySet) JWKS() JWKS {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	set := JWKS{Keys: make([]JWK, 0, len(ks.keys))}
+	// 新的放前面，方便人工檢視
+	for i := len(ks.keys) - 1; i >= 0; i-- {
+		jwk, err := NewJWK(ks.keys[i].ID, ks.keys[i].Public())
+		if err != nil {
+			log.Printf("⚠️ 無法輸出金鑰 %s：%v", ks.keys[i].ID, err)
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// StartRotation 啟動背景排程，定期重新讀取金鑰目錄、到期時產生新金鑰並清除過期金鑰
+func (ks *KeySet) StartRotation(ctx context.Context) {
+	interval := ks.rotation / 4
+	if interval > time.Hour {
+		interval = time.Hour
+	}
+	if interval < time.Minute {
+		interval = time.Minute
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				ks.mu.Lock()
+				// 多個 authsvc 共用金鑰目錄時，先讀取其他實例產生的金鑰
+				if err := ks.reload(); err != nil {
+					log.Printf("⚠️ 重新載入 JWT 金鑰失敗：%v", err)
+				}
+				if err := ks.rotateIfDue(); err != nil {
+					log.Printf("❌ JWT 金鑰輪替失敗：%v", err)
+				}
+				ks.mu.Unlock()
+			}
+		}
+	}()
+}
+
+// reload 讀取金鑰目錄中所有 .pem（呼叫前需持有寫鎖）
+func (ks *KeySet) reload() error {
+	paths, err := filepath.Glob(filepath.Join(ks.dir, "*.pem"))
+	if err != nil {
+		return err
+	}
+
+	keys := make([]*SigningKey, 0, len(paths))
+	for _, path := range paths {
+		key, err := readSigningKey(path)
+		if err != nil {
+			log.Printf("⚠️ 略過無法讀取的金鑰檔 %s：%v", path, err)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 && len(ks.keys) > 0 {
+		// 目錄被清空時保留記憶體中的金鑰，避免所有 token 立即失效
+		return errors.New("key directory is empty")
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	ks.keys = keys
+	return nil
+}
+
+// rotateIfDue 啟用中的金鑰到期就產生新金鑰，並移除退役超過 overlap 的舊金鑰（呼叫前需持有寫鎖）
+func (ks *KeySet) rotateIfDue() error {
+	if len(ks.keys) == 0 || time.Since(ks.keys[len(ks.keys)-1].CreatedAt) >= ks.rotation {
+		key, err := ks.generate()
+		if err != nil {
+			return err
+		}
+		ks.keys = append(ks.keys, key)
+		log.Printf("🔑 已產生新的 JWT 簽章金鑰 kid=%s (%s)", key.ID, key.Method.Alg())
+	}
+
+	// 第 i 把金鑰在第 i+1 把建立時退役
+	kept := ks.keys[:0]
+	for i, k := range ks.keys {
+		if i < len(ks.keys)-1 && time.Since(ks.keys[i+1].CreatedAt) > ks.overlap {
+			if err := os.Remove(ks.keyPath(k.ID)); err != nil && !os.IsNotExist(err) {
+				log.Printf("⚠️ 無法刪除過期金鑰 %s：%v", k.ID, err)
+			}
+			log.Printf("🔑 已移除過期的 JWT 簽章金鑰 kid=%s", k.ID)
+			continue
+		}
+		kept = append(kept, k)
+	}
+	ks.keys = kept
+	return nil
+}
+
+// generate 產生新金鑰並寫入金鑰目錄
+func (ks *KeySet) generate() (*SigningKey, error) {
+	var private crypto.Signer
+	var method jwt.SigningMethod
+	switch ks.alg {
+	case "EdDSA":
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		private, method = priv, jwt.SigningMethodEdDSA
+	default:
+		priv, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, err
+		}
+		private, method = priv, jwt.SigningMethodRS256
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	// kid 開頭帶建立時間，重新載入時可還原金鑰順序
+	kid := fmt.Sprintf("%d-%s", now.Unix(), NewTokenID()[:8])
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(ks.keyPath(kid), data, 0o600); err != nil {
+		return nil, err
+	}
+	return &SigningKey{ID: kid, Method: method, Private: private, CreatedAt: now}, nil
+}
+
+func (ks *KeySet) keyPath(kid string) string {
+	return filepath.Join(ks.dir, kid+".pem")
+}
+
+// readSigningKey 讀取單一 PEM 金鑰檔，kid 取自檔名
+func readSigningKey(path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid pem")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &SigningKey{ID: strings.TrimSuffix(filepath.Base(path), ".pem")}
+	switch priv := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Private, key.Method = priv, jwt.SigningMethodRS256
+	case ed25519.PrivateKey:
+		key.Private, key.Method = priv, jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", parsed)
+	}
+
+	// 由本程式產生的 kid 開頭為 unix 時間；手動放進來的金鑰則以檔案修改時間為準
+	if ts, err := strconv.ParseInt(strings.SplitN(key.ID, "-", 2)[0], 10, 64); err == nil {
+		key.CreatedAt = time.Unix(ts, 0)
+	} else if info, err := os.Stat(path); err == nil {
+		key.CreatedAt = info.ModTime()
+	}
+	return key, nil
+}
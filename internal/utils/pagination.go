@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+/**
+ * @File: pagination.go
+ * @Description:
+ *
+ * 列表 API 共用的分頁參數與回應格式
+ * 同時支援 offset（?limit=&offset=）與 cursor（?limit=&cursor=）兩種分頁；
+ * cursor 為不透明字串，內容由各列表自行定義，資料異動時不會跳頁或重複。
+ *
+ * @Author: Timmy
+ * @Create: 2026/10/23 下午2:00
+ * @Software: GoLand
+ * @Version:  1.0
+ */
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// PageRequest 分頁查詢參數（以 ShouldBindQuery 綁定）
+type PageRequest struct {
+	Limit  int    `json:"limit" form:"limit" binding:"omitempty,min=1,max=100" validateMsg:"min=limit 最小為 1,max=limit 最大為 100"`
+	Offset int    `json:"offset" form:"offset" binding:"omitempty,min=0" validateMsg:"min=offset 不可小於 0"`
+	Cursor string `json:"cursor" form:"cursor"`
+}
+
+// PageLimit 未指定時使用預設筆數
+func (p PageRequest) PageLimit() int {
+	if p.Limit <= 0 {
+		return DefaultPageLimit
+	}
+	return min(p.Limit, MaxPageLimit)
+}
+
+// Page 分頁回應：total 為符合條件的總筆數；has_more 時可用 next_cursor 取下一頁
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	Total      int64  `json:"total"`
+	Limit      int    `json:"limit"`
+	Offset     *int   `json:"offset,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+}
+
+// NewPage 組出分頁回應；items 需多查一筆（limit+1）以判斷是否還有下一頁，
+// cursorOf 由最後一筆資料產生下一頁的 cursor
+func NewPage[T any](items []T, total int64, req PageRequest, cursorOf func(T) (string, error)) (Page[T], error) {
+	limit := req.PageLimit()
+	page := Page[T]{Items: items, Total: total, Limit: limit}
+	if page.Items == nil {
+		page.Items = []T{}
+	}
+	if req.Cursor == "" {
+		offset := req.Offset
+		page.Offset = &offset
+	}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		page.HasMore = true
+		cursor, err := cursorOf(page.Items[limit-1])
+		if err != nil {
+			return page, err
+		}
+		page.NextCursor = cursor
+	}
+	return page, nil
+}
+
+// EncodeCursor 把 cursor 內容編成不透明字串
+func EncodeCursor(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// DecodeCursor 解析 EncodeCursor 產生的字串
+func DecodeCursor(cursor string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return ErrInvalidCursor
+	}
+	return nil
+}
@@ -22,9 +22,15 @@ type ErrorCode struct {
 }
 
 var (
-	CodeBadRequest   = ErrorCode{"4000", "Bad Request: Invalid format"}
-	CodeParamInvalid = ErrorCode{"4001", "Invalid parameters"}
-	CodeEmailExists  = ErrorCode{"4002", "Email already exists"}
-	CodeUnauthorized = ErrorCode{"4010", "Unauthorized"}
-	CodeServerError  = ErrorCode{"5000", "Internal server error"}
+	CodeBadRequest         = ErrorCode{"4000", "Bad Request: Invalid format"}
+	CodeParamInvalid       = ErrorCode{"4001", "Invalid parameters"}
+	CodeEmailExists        = ErrorCode{"4002", "Email already exists"}
+	CodeUnauthorized       = ErrorCode{"4010", "Unauthorized"}
+	CodeAccountInactive    = ErrorCode{"4011", "Account is inactive"}
+	CodeEmailNotVerified   = ErrorCode{"4012", "Email not verified"}
+	CodeInvalidCredentials = ErrorCode{"4013", "Invalid credentials"}
+	CodeForbidden          = ErrorCode{"4030", "Forbidden"}
+	CodeNotFound           = ErrorCode{"4040", "Resource not found"}
+	CodeTooManyRequests    = ErrorCode{"4290", "Too many requests"}
+	CodeServerError        = ErrorCode{"5000", "Internal server error"}
 )
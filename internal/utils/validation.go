@@ -16,6 +16,14 @@ import (
  * @Version:  1.0
  */
 
+// validationMessages 各驗證規則的預設錯誤訊息，欄位沒有在 validateMsg 指定時使用
+var validationMessages = map[string]string{}
+
+// RegisterValidationMessage 註冊驗證規則的預設錯誤訊息（於啟動時註冊驗證器時一併呼叫）
+func RegisterValidationMessage(tag string, msg string) {
+	validationMessages[tag] = msg
+}
+
 // ExtractFieldErrorMessages 根據傳入 struct 的欄位 tag `validateMsg`
 // 對 validator 驗證錯誤逐一解析，並組成一份 map[string]string 的欄位錯誤訊息集合。
 // 欄位名稱會轉換為對應的 `json` tag（如 "Username" -> "username"）。
@@ -44,9 +52,11 @@ func ExtractFieldErrorMessages(obj interface{}, errs validator.ValidationErrors)
 			tagMsg := f.Tag.Get("validateMsg")
 			msgMap := parseValidateMsgTag(tagMsg)
 
-			// 如果該驗證規則有自訂訊息，則使用
+			// 如果該驗證規則有自訂訊息，則使用；其次使用規則註冊的預設訊息
 			if msg, ok := msgMap[fieldErr.Tag()]; ok {
 				errMap[jsonTag] = msg
+			} else if msg, ok := validationMessages[fieldErr.Tag()]; ok {
+				errMap[jsonTag] = msg
 			} else {
 				// 否則給預設訊息
 				errMap[jsonTag] = "欄位格式錯誤：" + fieldErr.Tag()
@@ -2,7 +2,10 @@ package client
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"micro-golang/internal/dto"
+	"micro-golang/internal/utils"
 	"net/http"
 )
 
@@ -10,12 +13,37 @@ import (
  * @File: userclient.go
  * @Description:
  *
+ * 呼叫 usersvc 的客戶端，回應為統一的 utils.JsonResult 格式，status_code 不是成功時轉成 error
+ *
  * @Author: Timmy
  * @Create: 2025/4/23 上午11:12
  * @Software: GoLand
  * @Version:  1.0
  */
 
+// ErrUserNotFound usersvc 回傳找不到使用者
+var ErrUserNotFound = errors.New("user not found")
+
+// APIError usersvc 回傳的錯誤（status_code 為業務錯誤碼）
+type APIError struct {
+	HTTPStatus int
+	StatusCode string
+	Msg        string
+	MsgDetail  string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("user service error %s (HTTP %d): %s %s", e.StatusCode, e.HTTPStatus, e.Msg, e.MsgDetail)
+}
+
+// envelope utils.JsonResult，data 延後解析成呼叫端需要的型別
+type envelope struct {
+	StatusCode string          `json:"status_code"`
+	Msg        interface{}     `json:"msg"`
+	MsgDetail  string          `json:"msg_detail"`
+	Data       json.RawMessage `json:"data"`
+}
+
 type UserClient struct {
 	baseURL string
 }
@@ -24,18 +52,18 @@ func NewUserClient(baseURL string) *UserClient {
 	return &UserClient{baseURL: baseURL}
 }
 
-// 通用的 fetchData 函式，接收 http 方法作為參數
-func (uc *UserClient) fetchData(method string, urlPath string, token string) (map[string]interface{}, error) {
+// 通用的 fetchData 函式，接收 http 方法作為參數，成功時把 data 解析進 out
+func (uc *UserClient) fetchData(method string, urlPath string, token string, out interface{}) error {
 	url := fmt.Sprintf("%s%s", uc.baseURL, urlPath)
 	req, err := http.NewRequest(method, url, nil) // 使用傳入的 method
 	if err != nil {
-		return nil, err
+		return err
 	}
-	req.Header.Add("Authorization", fmt.Sprintf(token))
+	req.Header.Set("Authorization", token)
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
@@ -43,21 +71,38 @@ func (uc *UserClient) fetchData(method string, urlPath string, token string) (ma
 		}
 	}()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("請求失敗，狀態碼: %d", resp.StatusCode)
+	var result envelope
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("請求失敗，狀態碼: %d", resp.StatusCode)
 	}
-
-	var data map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return nil, err
+	if resp.StatusCode != http.StatusOK || result.StatusCode != utils.Success {
+		if result.StatusCode == utils.CodeNotFound.StatusCode {
+			return ErrUserNotFound
+		}
+		return &APIError{
+			HTTPStatus: resp.StatusCode,
+			StatusCode: result.StatusCode,
+			Msg:        fmt.Sprint(result.Msg),
+			MsgDetail:  result.MsgDetail,
+		}
 	}
-	return data, nil
+	return json.Unmarshal(result.Data, out)
 }
 
-func (uc *UserClient) FetchUser(id string, token string) (map[string]interface{}, error) {
-	return uc.fetchData("GET", fmt.Sprintf("/users/%s", id), token)
+// FetchUser 依 ID 查詢使用者
+func (uc *UserClient) FetchUser(id uint, token string) (*dto.UserLoginResponseDTO, error) {
+	var user dto.UserLoginResponseDTO
+	if err := uc.fetchData("GET", fmt.Sprintf("/users/%d", id), token, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
 }
 
-func (uc *UserClient) FetchUserEmail(id string, token string) (map[string]interface{}, error) {
-	return uc.fetchData("GET", fmt.Sprintf("/users/email/%s", id), token)
+// FetchUserEmail 依 ID 查詢使用者 Email
+func (uc *UserClient) FetchUserEmail(id uint, token string) (*dto.UserEmailDTO, error) {
+	var user dto.UserEmailDTO
+	if err := uc.fetchData("GET", fmt.Sprintf("/users/email/%d", id), token, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
 }